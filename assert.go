@@ -0,0 +1,77 @@
+package deep
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// AssertEqual compares want and got. If they are not equivalent, a report of
+// the differences is logged with t.Error, and the test continues. Returns
+// whether the values are equivalent.
+func (c Comparer) AssertEqual(t testing.TB, want, got interface{}) bool {
+	t.Helper()
+	diffs := c.Equal(want, got)
+	if diffs == nil {
+		return true
+	}
+	t.Error(c.message(want, got, diffs))
+	return false
+}
+
+// RequireEqual compares want and got. If they are not equivalent, a report of
+// the differences is logged with t.Fatal, which stops the test. Returns true
+// if the values are equivalent.
+func (c Comparer) RequireEqual(t testing.TB, want, got interface{}) bool {
+	t.Helper()
+	diffs := c.Equal(want, got)
+	if diffs == nil {
+		return true
+	}
+	t.Fatal(c.message(want, got, diffs))
+	return false
+}
+
+// message returns a human-readable report of the differences between want and
+// got. The first line describes the compared values, and each following line
+// describes one difference.
+func (c Comparer) message(want, got interface{}, diffs []Diff) string {
+	var b strings.Builder
+	wt, gt := reflect.TypeOf(want), reflect.TypeOf(got)
+	if wt == gt {
+		fmt.Fprintf(&b, "values of type %v differ", wt)
+	} else {
+		fmt.Fprintf(&b, "values of type %v (want) and %v (got) differ", wt, gt)
+	}
+	if len(diffs) == 1 {
+		b.WriteString(" (1 difference")
+	} else {
+		fmt.Fprintf(&b, " (%d differences", len(diffs))
+	}
+	if c.MaxDiffs > 0 && len(diffs) >= c.MaxDiffs {
+		b.WriteString(", further differences omitted")
+	}
+	b.WriteString("):")
+	for _, d := range diffs {
+		b.WriteString("\n\t")
+		b.WriteString(strings.Replace(d.String(), "\n", "\n\t", -1))
+	}
+	return b.String()
+}
+
+// AssertEqual compares want and got using the global configuration. If they
+// are not equivalent, a report of the differences is logged with t.Error, and
+// the test continues. Returns whether the values are equivalent.
+func AssertEqual(t testing.TB, want, got interface{}) bool {
+	t.Helper()
+	return Config.AssertEqual(t, want, got)
+}
+
+// RequireEqual compares want and got using the global configuration. If they
+// are not equivalent, a report of the differences is logged with t.Fatal,
+// which stops the test. Returns true if the values are equivalent.
+func RequireEqual(t testing.TB, want, got interface{}) bool {
+	t.Helper()
+	return Config.RequireEqual(t, want, got)
+}
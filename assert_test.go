@@ -0,0 +1,78 @@
+package deep
+
+import (
+	"fmt"
+	"testing"
+)
+
+// mockTB records the messages logged by assertions.
+type mockTB struct {
+	testing.TB
+	helpers int
+	errors  []string
+	fatals  []string
+}
+
+func (t *mockTB) Helper() {
+	t.helpers++
+}
+
+func (t *mockTB) Error(args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprint(args...))
+}
+
+func (t *mockTB) Fatal(args ...interface{}) {
+	t.fatals = append(t.fatals, fmt.Sprint(args...))
+}
+
+func TestAssertEqual(t *testing.T) {
+	c := newComparer("MaxDiffs", 2)
+
+	m := &mockTB{}
+	if !c.AssertEqual(m, basic{1, 2}, basic{1, 2}) {
+		t.Errorf("want true for equal values")
+	}
+	if len(m.errors) != 0 || m.helpers == 0 {
+		t.Errorf("unexpected state %+v", m)
+	}
+
+	m = &mockTB{}
+	if c.AssertEqual(m, basic{1, 2}, basic{2, 2}) {
+		t.Errorf("want false for unequal values")
+	}
+	want := "values of type deep.basic differ (1 difference):\n\tstruct.X: 1 != 2"
+	if len(m.errors) != 1 || m.errors[0] != want {
+		t.Errorf("want error %q, got %q", want, m.errors)
+	}
+
+	m = &mockTB{}
+	c.AssertEqual(m, []int{1, 2, 3}, []string{"a"})
+	want = "values of type []int (want) and []string (got) differ (1 difference):\n\t[]int != []string"
+	if len(m.errors) != 1 || m.errors[0] != want {
+		t.Errorf("want error %q, got %q", want, m.errors)
+	}
+
+	m = &mockTB{}
+	c.AssertEqual(m, [3]int{1, 2, 3}, [3]int{4, 5, 6})
+	want = "values of type [3]int differ (2 differences, further differences omitted):\n\tarray[0]: 1 != 4\n\tarray[1]: 2 != 5"
+	if len(m.errors) != 1 || m.errors[0] != want {
+		t.Errorf("want error %q, got %q", want, m.errors)
+	}
+}
+
+func TestRequireEqual(t *testing.T) {
+	m := &mockTB{}
+	if !RequireEqual(m, 1, 1) {
+		t.Errorf("want true for equal values")
+	}
+	if RequireEqual(m, 1, 2) {
+		t.Errorf("want false for unequal values")
+	}
+	want := "values of type int differ (1 difference):\n\t1 != 2"
+	if len(m.fatals) != 1 || m.fatals[0] != want || len(m.errors) != 0 {
+		t.Errorf("want fatal %q, got %+v", want, m)
+	}
+	if m.helpers < 2 {
+		t.Errorf("want Helper to be called by each function, got %d calls", m.helpers)
+	}
+}
@@ -0,0 +1,129 @@
+package deep
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ColorMode determines whether rendered differences are colored with ANSI
+// escape sequences.
+type ColorMode int
+
+const (
+	// ColorAuto colors output written to a terminal, unless the NO_COLOR
+	// environment variable is set to a non-empty value, or the TERM
+	// environment variable is "dumb".
+	ColorAuto ColorMode = iota
+	// ColorNever never colors output.
+	ColorNever
+	// ColorAlways always colors output.
+	ColorAlways
+)
+
+var colorModeStrings = [...]string{
+	ColorAuto:   "Auto",
+	ColorNever:  "Never",
+	ColorAlways: "Always",
+}
+
+// String returns a string representation of the mode.
+func (m ColorMode) String() string {
+	if m < 0 || int(m) >= len(colorModeStrings) {
+		return fmt.Sprintf("ColorMode(%d)", int(m))
+	}
+	return colorModeStrings[m]
+}
+
+// ANSI escape sequences used to color output.
+const (
+	ansiReset = "\x1b[0m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiDim   = "\x1b[2m"
+)
+
+// styler applies styles to the parts of rendered differences.
+type styler struct {
+	color bool
+}
+
+// styler returns a styler for output written to w, according to the Color
+// setting.
+func (c Comparer) styler(w io.Writer) styler {
+	switch c.Color {
+	case ColorNever:
+		return styler{}
+	case ColorAlways:
+		return styler{color: true}
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return styler{}
+	}
+	return styler{color: isTerminal(w)}
+}
+
+// isTerminal returns whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// apply wraps each line of s with the given escape sequence.
+func (st styler) apply(seq, s string) string {
+	if !st.color || s == "" {
+		return s
+	}
+	return seq + strings.Replace(s, "\n", ansiReset+"\n"+seq, -1) + ansiReset
+}
+
+// path styles a path.
+func (st styler) path(s string) string { return st.apply(ansiDim, s) }
+
+// left styles a part of the left value.
+func (st styler) left(s string) string { return st.apply(ansiRed, s) }
+
+// right styles a part of the right value.
+func (st styler) right(s string) string { return st.apply(ansiGreen, s) }
+
+// detail styles a line-based diff, coloring removed and added lines.
+func (st styler) detail(s string) string {
+	if !st.color {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = st.path(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = st.left(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = st.right(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteDiffs writes a human-readable representation of each diff to w, each
+// followed by a newline. If the output is colored according to the Color
+// setting, then paths are dimmed, left values are red, and right values are
+// green. Otherwise, each line is the same as Diff.String.
+func (c Comparer) WriteDiffs(w io.Writer, diffs []Diff) error {
+	st := c.styler(w)
+	var b strings.Builder
+	for _, d := range diffs {
+		b.WriteString(d.format(st))
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,78 @@
+package deep
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteDiffs(t *testing.T) {
+	c := newComparer("MaxDiffs", 100, "TextDiffLength", 10, "TextDiffContext", 1)
+	// Maps of one entry each, so that the order of the diffs is fixed.
+	diffs := append(
+		c.Equal(map[string]string{"a": "x"}, map[string]string{"a": "y"}),
+		c.Equal(map[string]string{"b": "1\n2"}, map[string]string{"b": "1\n3"})...,
+	)
+
+	var b strings.Builder
+	if err := c.WriteDiffs(&b, diffs); err != nil {
+		t.Fatal(err)
+	}
+	want := diffs[0].String() + "\n" + diffs[1].String() + "\n"
+	if b.String() != want {
+		t.Errorf("want uncolored output\n%s\ngot\n%s", want, b.String())
+	}
+
+	c.Color = ColorAlways
+	b.Reset()
+	if err := c.WriteDiffs(&b, diffs); err != nil {
+		t.Fatal(err)
+	}
+	want = "" +
+		"\x1b[2mmap[a]\x1b[0m: \x1b[31mx\x1b[0m != \x1b[32my\x1b[0m (first difference at byte 0)\n" +
+		"\x1b[2mmap[b]\x1b[0m:\n" +
+		"\x1b[31m--- left\x1b[0m\n" +
+		"\x1b[32m+++ right\x1b[0m\n" +
+		"\x1b[2m@@ -1,2 +1,2 @@\x1b[0m\n" +
+		" 1\n" +
+		"\x1b[31m-2\x1b[0m\n" +
+		"\x1b[32m+3\x1b[0m\n"
+	if b.String() != want {
+		t.Errorf("want colored output\n%q\ngot\n%q", want, b.String())
+	}
+}
+
+func TestTreeDiffColor(t *testing.T) {
+	c := newComparer("MaxDiffs", 100, "Color", ColorAlways)
+	want := "" +
+		"\x1b[2m  deep.basic{\x1b[0m\n" +
+		"\x1b[31m- \tX: 1,\x1b[0m\n" +
+		"\x1b[32m+ \tX: 2,\x1b[0m\n" +
+		"\x1b[2m  \t... // 1 unchanged field\x1b[0m\n" +
+		"\x1b[2m  }\x1b[0m"
+	if got := c.TreeDiff(basic{1, 2}, basic{2, 2}); got != want {
+		t.Errorf("want\n%q\ngot\n%q", want, got)
+	}
+}
+
+func TestColorAuto(t *testing.T) {
+	c := newComparer("Color", ColorAuto)
+	if c.styler(&strings.Builder{}).color {
+		t.Errorf("want no color for non-file writer")
+	}
+	f, err := os.CreateTemp("", "deep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if c.styler(f).color {
+		t.Errorf("want no color for regular file")
+	}
+
+	c.Color = ColorAlways
+	t.Setenv("NO_COLOR", "1")
+	if !c.styler(f).color {
+		t.Errorf("want ColorAlways to override NO_COLOR")
+	}
+}
@@ -0,0 +1,58 @@
+package deep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrMaxNodes indicates that a comparison visited more values than allowed by
+// MaxNodes.
+var ErrMaxNodes = errors.New("maximum number of nodes visited")
+
+// contextInterval is the number of values visited between checks of whether
+// the context of a comparison is done.
+const contextInterval = 64
+
+// EqualContext is like Equal, but stops the comparison when ctx is done, or
+// when more than MaxNodes values have been visited. A time budget can be
+// applied with context.WithTimeout.
+//
+// If the comparison is stopped, then the differences found so far are
+// returned, along with an error that wraps ErrMaxNodes or the error of ctx.
+// Values that were not visited are not considered equivalent, nor are they
+// reported like values below MaxDepth with ReportMaxDepth, so the error must
+// be checked before the values are assumed to be equivalent.
+func (c Comparer) EqualContext(ctx context.Context, x, y interface{}) ([]Diff, error) {
+	state := c.compare(ctx, x, y, false)
+	if len(state.result) == 0 {
+		return nil, state.err
+	}
+	return state.result, state.err
+}
+
+// stopped counts a visit to a value, and returns whether the comparison has
+// been stopped.
+func (s *compareState) stopped() bool {
+	if s.err != nil {
+		return true
+	}
+	s.nodes++
+	if s.maxNodes > 0 && s.nodes > s.maxNodes {
+		s.stop(ErrMaxNodes)
+	} else if s.ctx != nil && s.nodes%contextInterval == 0 {
+		if err := s.ctx.Err(); err != nil {
+			s.stop(err)
+		}
+	}
+	return s.err != nil
+}
+
+// stop stops the comparison at the current path because of err.
+func (s *compareState) stop(err error) {
+	if len(s.stack) == 0 {
+		s.err = fmt.Errorf("deep: comparison stopped: %w", err)
+		return
+	}
+	s.err = fmt.Errorf("deep: comparison stopped at %s: %w", s.stack, err)
+}
@@ -0,0 +1,93 @@
+package deep
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEqualContext(t *testing.T) {
+	type node struct{ A, B int }
+	x := make([]node, 100)
+	y := make([]node, 100)
+	for i := range y {
+		y[i] = node{i + 1, i + 1}
+	}
+	c := newComparer("MaxDiffs", 0, "CompareSlicesByIndex", true)
+
+	diffs, err := c.EqualContext(context.Background(), x, y)
+	if err != nil || len(diffs) != 200 {
+		t.Errorf("want 200 diffs and no error, got %d diffs and %v", len(diffs), err)
+	}
+	if diffs, err := c.EqualContext(context.Background(), x, x); diffs != nil || err != nil {
+		t.Errorf("want no diffs and no error, got %v, %v", diffs, err)
+	}
+
+	// Node budget.
+	c.MaxNodes = 10
+	diffs, err = c.EqualContext(context.Background(), x, y)
+	if !errors.Is(err, ErrMaxNodes) {
+		t.Errorf("want ErrMaxNodes, got %v", err)
+	}
+	if len(diffs) != 6 {
+		t.Errorf("want 6 diffs, got %d", len(diffs))
+	}
+	if want := "deep: comparison stopped at slice[3]: maximum number of nodes visited"; err == nil || err.Error() != want {
+		t.Errorf("want error %q, got %v", want, err)
+	}
+	// Values that were not visited are not equivalent.
+	if diffs, err := c.EqualContext(context.Background(), x, append([]node(nil), x...)); diffs != nil || !errors.Is(err, ErrMaxNodes) {
+		t.Errorf("want no diffs and ErrMaxNodes, got %v, %v", diffs, err)
+	}
+	if diffs := c.Equal(x, y); len(diffs) != 200 {
+		t.Errorf("want MaxNodes ignored by Equal, got %d diffs", len(diffs))
+	}
+	c.MaxNodes = 1000
+	if diffs, err := c.EqualContext(context.Background(), x, y); err != nil || len(diffs) != 200 {
+		t.Errorf("want 200 diffs and no error, got %d diffs and %v", len(diffs), err)
+	}
+	c.MaxNodes = 0
+
+	// Cancellation.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	diffs, err = c.EqualContext(ctx, x, y)
+	if diffs != nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("want no diffs and context.Canceled, got %d diffs and %v", len(diffs), err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	d := c
+	calls := 0
+	d.RegisterFunc(func(x, y node) bool {
+		if calls++; calls == 10 {
+			cancel()
+		}
+		return x == y
+	})
+	diffs, err = d.EqualContext(ctx, x, y)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want context.Canceled, got %v", err)
+	}
+	if len(diffs) < 9 || len(diffs) > 9+contextInterval {
+		t.Errorf("want diffs found before cancellation, got %d", len(diffs))
+	}
+
+	// Time budget.
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	d = c
+	d.RegisterFunc(func(x, y node) bool {
+		time.Sleep(100 * time.Microsecond)
+		return x == y
+	})
+	start := time.Now()
+	if _, err := d.EqualContext(ctx, make([]node, 100000), make([]node, 100000)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("want comparison stopped promptly, took %s", elapsed)
+	}
+}
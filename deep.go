@@ -1,15 +1,30 @@
 // The deep package provides deep comparisons with options.
+//
+// The package follows semantic versioning. Within a major version, exported
+// identifiers, the fields of exported structs, and the differences reported
+// for a given comparison remain compatible. Output meant to be read by
+// humans, such as the results of String methods, may change in any release.
+// See the README for the full compatibility policy.
 package deep
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"math/big"
+	"math/cmplx"
 	"reflect"
 	"strings"
 	"unsafe"
 )
 
 // Comparer configures how comparisons are made.
+//
+// A Comparer created with NewComparer caches information about the types it
+// compares, for each of its recent configurations of registered functions and
+// ignored types. Other Comparers use a cache shared between them. Comparisons
+// may be made concurrently from multiple goroutines, as long as the Comparer
+// is not modified at the same time.
 type Comparer struct {
 	// CompareUnexportedFields, when true, causes unexported fields to be
 	// compared.
@@ -18,19 +33,112 @@ type Comparer struct {
 	// comparing floats. A value of zero or less uses an exact equality
 	// comparison.
 	FloatPrecision int
+	// FloatAbsTolerance, when greater than zero, causes floats to be
+	// equivalent when the absolute difference between them is no greater than
+	// the tolerance.
+	FloatAbsTolerance float64
+	// FloatRelTolerance, when greater than zero, causes floats to be
+	// equivalent when the absolute difference between them is no greater than
+	// the tolerance multiplied by the larger of their magnitudes.
+	FloatRelTolerance float64
+	// FloatULPs, when greater than zero, causes floats to be equivalent when
+	// they are separated by no more than the given number of representable
+	// values of their type (units in the last place).
+	//
+	// Each of FloatPrecision, FloatAbsTolerance, FloatRelTolerance, and
+	// FloatULPs is a separate criterion. Floats are equivalent if they are
+	// equal, or if any criterion that is enabled considers them equivalent.
+	// The real and imaginary parts of complex numbers are compared
+	// separately.
+	FloatULPs int
 	// MaxDepth specifies the maximum depth below which values will be
-	// automatically be considered equal. A value of zero or less indicates an
-	// infinite maxmimum depth.
+	// automatically be considered equal, unless ReportMaxDepth is true. A
+	// value of zero or less indicates an infinite maxmimum depth. To bound
+	// the cost of a comparison, use MaxNodes with EqualContext instead.
 	MaxDepth int
+	// ReportMaxDepth, when true, causes values below MaxDepth to be reported
+	// with a DepthExceeded diff, rather than being considered equal. Each
+	// diff is located at the first value that was not compared.
+	ReportMaxDepth bool
 	// MaxDiffs specifies the maximum number of diffs to be returned. A value of
 	// zero or less indicates an infinite maximum amount.
 	MaxDiffs int
+	// MaxNodes specifies the maximum number of values visited by EqualContext
+	// before the comparison is stopped. A value of zero or less indicates an
+	// infinite maximum amount. Other methods ignore MaxNodes.
+	MaxNodes int
 	// NilMapsAreEmpty, when true, causes nil maps to be equal to maps with zero
 	// elements.
 	NilMapsAreEmpty bool
 	// NilSlicesAreEmpty, when true, causes nil slices to be equal to slices
 	// with zero elements.
 	NilSlicesAreEmpty bool
+	// UseEqualMethods, when true, causes values that have an Equal method to
+	// be compared by calling the method. The method must have the form
+	// "Equal(T) bool", where T is the type of the value, and may be defined
+	// on T or *T. If the method returns false, a single ValueMismatch is
+	// produced. Functions registered with RegisterFunc take precedence.
+	UseEqualMethods bool
+	// IgnorePaths is a list of path patterns. Values located at a path
+	// matching any of the patterns are not compared. A pattern is written in
+	// the same form as the string representation of a Path. For example,
+	// ".Users[*].CreatedAt" matches the CreatedAt field of every element of
+	// Users. In addition to field names, indices, and map keys, "*" matches
+	// any field or element. Equal panics if a pattern is malformed.
+	IgnorePaths []string
+	// IgnoreTypes is a list of types. Values of any of the types are not
+	// compared.
+	IgnoreTypes []reflect.Type
+	// UnorderedSlices, when true, causes slices to be compared without regard
+	// to the order of their elements. Each element of one slice is matched
+	// with an equivalent element of the other slice, and only elements without
+	// a match are reported.
+	UnorderedSlices bool
+	// UnorderedTypes is a list of slice types. Slices of any of the types are
+	// compared as though UnorderedSlices were true.
+	UnorderedTypes []reflect.Type
+	// UnorderedPaths is a list of path patterns, in the same form as
+	// IgnorePaths. Slices located at a path matching any of the patterns are
+	// compared as though UnorderedSlices were true.
+	UnorderedPaths []string
+	// DeepMapKeys, when true, causes the keys of maps to be matched by
+	// equivalence, rather than by identity. This allows keys that are not
+	// equal to themselves, such as NaN, to be matched, and pointer keys to be
+	// matched by the values they point to. Keys are compared with the same
+	// configuration as values. Each key of one map is matched with at most
+	// one key of the other map, preferring an identical key.
+	DeepMapKeys bool
+	// CompareSlicesByIndex, when true, causes slices to be compared element
+	// by element, by index. Otherwise, elements are matched using a minimal
+	// edit script, so that an element inserted into or removed from a slice
+	// is reported as a single ExtraElement or MissingElement, rather than as
+	// a difference at every following index. Arrays, having a fixed length,
+	// are always compared by index.
+	CompareSlicesByIndex bool
+	// TextDiffLength, when greater than zero, causes differing strings to be
+	// described in detail. If either string is at least TextDiffLength bytes
+	// long, or contains a newline, then Diff.Detail contains a line-based
+	// diff between the strings, in the unified format. Otherwise, Diff.Detail
+	// contains the offset of the first byte that differs.
+	TextDiffLength int
+	// TextDiffContext is the number of unchanged lines included around the
+	// changed lines of a line-based diff.
+	TextDiffContext int
+	// Overrides is a list of configurations that apply to parts of the
+	// compared values. See Override for details.
+	Overrides []Override
+	// Color determines whether differences rendered by WriteDiffs and
+	// WriteTreeDiff are colored.
+	Color ColorMode
+
+	// funcs maps a type to a function registered with RegisterFunc.
+	funcs map[reflect.Type]reflect.Value
+	// keys maps an element type to a key registered with RegisterKey or
+	// RegisterKeyField.
+	keys map[reflect.Type]sliceKey
+	// cache holds the plans of compared types, or is nil if the Comparer was
+	// not created by NewComparer.
+	cache *planCache
 }
 
 // NewComparer returns a new Comparer with a sensible default configuration.
@@ -41,61 +149,281 @@ func NewComparer() *Comparer {
 	return &Comparer{
 		CompareUnexportedFields: false,
 		FloatPrecision:          34, // Close to 1e-10.
+		FloatAbsTolerance:       0,
+		FloatRelTolerance:       0,
+		FloatULPs:               0,
 		MaxDepth:                0,
+		ReportMaxDepth:          false,
 		MaxDiffs:                10,
+		MaxNodes:                0,
 		NilMapsAreEmpty:         false,
 		NilSlicesAreEmpty:       false,
+		UseEqualMethods:         false,
+		IgnorePaths:             nil,
+		IgnoreTypes:             nil,
+		UnorderedSlices:         false,
+		UnorderedTypes:          nil,
+		UnorderedPaths:          nil,
+		DeepMapKeys:             false,
+		CompareSlicesByIndex:    false,
+		TextDiffLength:          80,
+		TextDiffContext:         3,
+		Overrides:               nil,
+		Color:                   ColorAuto,
+		cache:                   new(planCache),
+	}
+}
+
+// DiffKind indicates the kind of a Diff.
+type DiffKind int
+
+const (
+	// ValueMismatch indicates that two values of the same type are not
+	// equivalent.
+	ValueMismatch DiffKind = iota
+	// TypeMismatch indicates that two values have different types.
+	TypeMismatch
+	// NilMismatch indicates that one value is nil while the other is not.
+	NilMismatch
+	// MissingKey indicates that a map key is present in the left value, but
+	// not in the right value.
+	MissingKey
+	// ExtraKey indicates that a map key is present in the right value, but
+	// not in the left value.
+	ExtraKey
+	// MissingElement indicates that an element of an array or slice is
+	// present in the left value, but not in the right value.
+	MissingElement
+	// ExtraElement indicates that an element of an array or slice is present
+	// in the right value, but not in the left value.
+	ExtraElement
+	// DepthExceeded indicates that two values were not compared because they
+	// are located below MaxDepth. It is reported only when ReportMaxDepth is
+	// true.
+	DepthExceeded
+)
+
+var diffKindStrings = [...]string{
+	ValueMismatch:  "ValueMismatch",
+	TypeMismatch:   "TypeMismatch",
+	NilMismatch:    "NilMismatch",
+	MissingKey:     "MissingKey",
+	ExtraKey:       "ExtraKey",
+	MissingElement: "MissingElement",
+	ExtraElement:   "ExtraElement",
+	DepthExceeded:  "DepthExceeded",
+}
+
+// String returns a string representation of the kind.
+func (k DiffKind) String() string {
+	if k < 0 || int(k) >= len(diffKindStrings) {
+		return fmt.Sprintf("DiffKind(%d)", int(k))
 	}
+	return diffKindStrings[k]
 }
 
 // Diff represents a single unit of difference between two values.
 type Diff struct {
-	left  string
-	right string
-	stack string
+	// Kind indicates the kind of difference.
+	Kind DiffKind
+	// Path is the location of the difference, relative to the compared
+	// values.
+	Path Path
+	// Left is the value from the left side of the comparison. It is invalid
+	// if the value is absent, such as with an ExtraKey, or when the left
+	// side is an untyped nil.
+	Left reflect.Value
+	// Right is the value from the right side of the comparison. It is
+	// invalid if the value is absent, such as with a MissingKey, or when the
+	// right side is an untyped nil.
+	//
+	// With CompareUnexportedFields, Left and Right may be obtained through
+	// unexported fields. Such values can be used with Interface, except when
+	// they are located within a map or interface, in which case Interface
+	// panics, and CanInterface returns false.
+	Right reflect.Value
+	// Delta is the absolute difference between Left and Right, for a
+	// ValueMismatch between floating-point or complex numbers. Otherwise, it
+	// is zero.
+	Delta float64
+	// Detail is an extended description of the difference, such as a
+	// line-based diff between two strings. It is empty if no such
+	// description is available.
+	Detail string
+
+	// The left and right byte slices containing the difference, if it is
+	// located at an element of one. Used by JSONPatch, since byte slices are
+	// encoded as strings.
+	bytes [2]reflect.Value
 }
 
 // String returns a string representation of the diff. The returned string is
 // meant to be read by humans, so it is not guaranteed to be consistent.
 func (d Diff) String() string {
-	if d.stack != "" {
-		return d.stack + ": " + d.left + " != " + d.right
+	return d.format(styler{})
+}
+
+// format returns a string representation of the diff, styled by st.
+func (d Diff) format(st styler) string {
+	var left, right string
+	switch d.Kind {
+	case TypeMismatch:
+		left, right = formatType(d.Left), formatType(d.Right)
+	case MissingKey:
+		left, right = formatValue(d.Left), "<no key>"
+	case ExtraKey:
+		left, right = "<no key>", formatValue(d.Right)
+	case MissingElement:
+		left, right = formatValue(d.Left), "<no value>"
+	case ExtraElement:
+		left, right = "<no value>", formatValue(d.Right)
+	default:
+		left, right = formatValue(d.Left), formatValue(d.Right)
 	}
-	return d.left + " != " + d.right
+	path := st.path(d.Path.String())
+	if strings.Contains(d.Detail, "\n") {
+		// Multi-line details replace the values entirely.
+		detail := st.detail(d.Detail)
+		if path != "" {
+			return path + ":\n" + detail
+		}
+		return detail
+	}
+	s := st.left(left) + " != " + st.right(right)
+	if d.Kind == DepthExceeded {
+		s = "not compared beyond MaxDepth"
+	}
+	if d.Delta != 0 && !math.IsNaN(d.Delta) && !math.IsInf(d.Delta, 0) {
+		s += fmt.Sprintf(" (delta %g)", d.Delta)
+	}
+	if d.Detail != "" {
+		s += " (" + d.Detail + ")"
+	}
+	if path != "" {
+		return path + ": " + s
+	}
+	return s
+}
+
+// formatType returns a string representation of the type of v.
+func formatType(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<nil>"
+	}
+	return v.Type().String()
+}
+
+// formatValue returns a string representation of v.
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<nil>"
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return "<nil slice>"
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return "<nil map>"
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return "<nil " + v.Type().String() + ">"
+		}
+	}
+	return fmt.Sprintf("%v", v)
 }
 
 type compareState struct {
 	Comparer
-	result  []Diff
-	stack   []string
-	visited map[visit]struct{}
-	floatx  *big.Float
-	floaty  *big.Float
+	result      []Diff
+	stack       Path
+	visited     map[visit]struct{}
+	floatx      *big.Float
+	floaty      *big.Float
+	ignorePaths []pattern
+	// Patterns parsed from UnorderedPaths.
+	unorderedPaths []pattern
+	// The number of active calls to equivalent.
+	probes int
+	// Visits made while probing, to be forgotten once probing completes.
+	probed []visit
+	// Patterns parsed from Overrides.
+	overridePaths []pattern
+	// Configurations replaced by overrides, to be restored when the path of
+	// the override is popped.
+	saved []savedConfig
+	// The number of differences found, including those not recorded in
+	// result.
+	ndiffs int
+	// Whether differences are only counted, rather than recorded.
+	quiet bool
+	// The context of EqualContext, or nil if it cannot be done.
+	ctx context.Context
+	// The maximum number of values to visit, and the number visited.
+	maxNodes int
+	nodes    int
+	// The reason the comparison was stopped.
+	err error
+	// The plans of types under the current configuration.
+	plans *configPlans
+	// The byte slices whose elements are being compared, if any.
+	bytes [2]reflect.Value
 }
 
-func (s *compareState) push(v, i string) {
-	if len(s.stack) == 0 {
-		s.stack = append(s.stack, v+i)
+// full returns whether the maximum number of differences has been found, or
+// the comparison was stopped.
+func (s *compareState) full() bool {
+	return s.err != nil || s.MaxDiffs > 0 && s.ndiffs >= s.MaxDiffs
+}
+
+func (s *compareState) push(step Step) {
+	s.stack = append(s.stack, step)
+	switch step.Kind {
+	case IndirectStep, ElemStep:
+		// Matches the same patterns as the previous step.
 		return
 	}
-	s.stack = append(s.stack, i)
+	for i, p := range s.overridePaths {
+		if p.match(s.stack) {
+			s.override(s.Overrides[i])
+		}
+	}
 }
 
 func (s *compareState) pop() {
+	for len(s.saved) > 0 && s.saved[len(s.saved)-1].depth == len(s.stack) {
+		s.restore()
+	}
 	s.stack = s.stack[:len(s.stack)-1]
 }
 
-func (s *compareState) append(x, y interface{}) {
-	if i, ok := x.(reflect.Value); ok && i.IsValid() {
-		x = i.Interface()
+func (s *compareState) append(kind DiffKind, x, y reflect.Value) {
+	s.ndiffs++
+	if s.quiet {
+		return
 	}
-	if i, ok := y.(reflect.Value); ok && i.IsValid() {
-		y = i.Interface()
+	path := make(Path, len(s.stack))
+	copy(path, s.stack)
+	// Allow values obtained through unexported fields to be used with
+	// Interface, where possible.
+	if x.IsValid() {
+		if v, ok := exportValue(x); ok {
+			x = v
+		}
+	}
+	if y.IsValid() {
+		if v, ok := exportValue(y); ok {
+			y = v
+		}
 	}
 	s.result = append(s.result, Diff{
-		left:  fmt.Sprintf("%v", x),
-		right: fmt.Sprintf("%v", y),
-		stack: strings.Join(s.stack, ""),
+		Kind:  kind,
+		Path:  path,
+		Left:  x,
+		Right: y,
+		bytes: s.bytes,
 	})
 }
 
@@ -109,35 +437,88 @@ func (s *compareState) append(x, y interface{}) {
 // configurable options, there are several other differences:
 //
 //     - NaN is equivalent to NaN.
+//     - Struct fields with the tag `deep:"-"` are not compared.
+//     - Map entries are compared in a deterministic order of their keys, so
+//       that the order of differences is consistent between comparisons.
 //     - Because of quirks with maps, maps containing NaN keys can be reported
-//       incorrectly.
+//       incorrectly, unless DeepMapKeys is true.
 func (c Comparer) Equal(x, y interface{}) []Diff {
-	if x == nil && y == nil {
+	state := c.compare(nil, x, y, false)
+	if len(state.result) == 0 {
 		return nil
 	}
+	return state.result
+}
+
+// Equivalent returns whether x and y are equivalent according to the current
+// configuration. It is equivalent to checking whether Equal returns nil, but
+// stops at the first difference, and does not record the differences.
+func (c Comparer) Equivalent(x, y interface{}) bool {
+	c.MaxDiffs = 1
+	return c.compare(nil, x, y, true).ndiffs == 0
+}
 
-	state := compareState{
+// compare compares x and y, returning the resulting state. If quiet is true,
+// then differences are only counted. If ctx is not nil, then the comparison
+// stops when ctx is done, or when more than MaxNodes values are visited.
+func (c Comparer) compare(ctx context.Context, x, y interface{}, quiet bool) *compareState {
+	state := &compareState{
 		Comparer: c,
-		visited:  make(map[visit]struct{}),
+		quiet:    quiet,
 	}
-	if state.FloatPrecision > 0 {
-		state.floatx = new(big.Float).SetPrec(uint(state.FloatPrecision))
-		state.floaty = new(big.Float).SetPrec(uint(state.FloatPrecision))
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			state.stop(err)
+			return state
+		}
+		if ctx.Done() != nil {
+			state.ctx = ctx
+		}
+		state.maxNodes = c.MaxNodes
+	}
+	if x == nil && y == nil {
+		return state
 	}
+	state.visited = make(map[visit]struct{})
+	state.configure()
 
 	if x == nil && y != nil {
-		state.append("<nil>", y)
-		return state.result
+		state.append(NilMismatch, reflect.Value{}, reflect.ValueOf(y))
+		return state
 	} else if x != nil && y == nil {
-		state.append(x, "<nil>")
-		return state.result
+		state.append(NilMismatch, reflect.ValueOf(x), reflect.Value{})
+		return state
 	}
 
-	state.deepValueEqual(reflect.ValueOf(x), reflect.ValueOf(y), 0)
-	if len(state.result) == 0 {
-		return nil
+	vx, vy := reflect.ValueOf(x), reflect.ValueOf(y)
+	if len(state.funcs) > 0 || state.UseEqualMethods || state.CompareUnexportedFields || len(state.Overrides) > 0 {
+		// Allow registered functions, Equal methods, and patches to receive
+		// values obtained through unexported fields.
+		vx, vy = addressable(vx), addressable(vy)
 	}
-	return state.result
+	state.deepValueEqual(vx, vy, 0)
+	return state
+}
+
+// ignore returns whether the values x and y, located at the current path, are
+// ignored. Invalid values are not considered.
+func (s *compareState) ignore(x, y reflect.Value) bool {
+	if len(s.IgnoreTypes) > 0 && (x.IsValid() && s.plan(x.Type()).ignored || y.IsValid() && s.plan(y.Type()).ignored) {
+		return true
+	}
+	for _, p := range s.ignorePaths {
+		if p.match(s.stack) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoring returns whether any ignore rules may apply, which can cause
+// differences to be ignored. Overrides are included, since they may add ignore
+// rules.
+func (s *compareState) ignoring() bool {
+	return len(s.IgnoreTypes) > 0 || len(s.ignorePaths) > 0 || len(s.overridePaths) > 0
 }
 
 type visit struct {
@@ -147,33 +528,49 @@ type visit struct {
 }
 
 func (s *compareState) deepValueEqual(x, y reflect.Value, depth int) bool {
+	if s.stopped() {
+		return false
+	}
+
+	if s.ignore(x, y) {
+		return true
+	}
+
 	if s.MaxDepth > 0 && depth > s.MaxDepth {
+		// Probes treat the values as equivalent, so that elements are
+		// matched the same as without ReportMaxDepth.
+		if s.ReportMaxDepth && s.probes == 0 {
+			s.append(DepthExceeded, x, y)
+			return false
+		}
 		return true
 	}
 
 	if !x.IsValid() || !y.IsValid() {
-		if !x.IsValid() && y.IsValid() {
-			s.append("<nil>", y.Type())
-		} else if x.IsValid() && !y.IsValid() {
-			s.append(x.Type(), "<nil>")
+		if x.IsValid() != y.IsValid() {
+			s.append(TypeMismatch, x, y)
 		}
 		return x.IsValid() == y.IsValid()
 	}
 
 	if x.Type() != y.Type() {
-		s.append(x.Type(), y.Type())
+		s.append(TypeMismatch, x, y)
 		return false
 	}
 
-	hard := func(k reflect.Kind) bool {
-		switch k {
-		case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
-			return true
+	plan := s.plan(x.Type())
+	if plan.hasFunc {
+		if eq, ok := s.callFunc(plan.fn, x, y); ok {
+			return eq
+		}
+	}
+	if s.UseEqualMethods {
+		if eq, ok := s.callEqualMethod(plan, x, y); ok {
+			return eq
 		}
-		return false
 	}
 
-	if x.CanAddr() && y.CanAddr() && hard(x.Kind()) {
+	if x.CanAddr() && y.CanAddr() && plan.cyclic {
 		addr1 := unsafe.Pointer(x.UnsafeAddr())
 		addr2 := unsafe.Pointer(y.UnsafeAddr())
 		if uintptr(addr1) > uintptr(addr2) {
@@ -184,15 +581,27 @@ func (s *compareState) deepValueEqual(x, y reflect.Value, depth int) bool {
 			return true
 		}
 		s.visited[v] = struct{}{}
+		if s.probes > 0 {
+			s.probed = append(s.probed, v)
+		}
 	}
 
 	switch x.Kind() {
 	case reflect.Array:
+		plain := s.plain(x.Type().Elem(), depth+1)
+		if plain {
+			if eq, ok := sameMemory(x, y); ok && eq {
+				return true
+			}
+		}
 		for i := 0; i < x.Len(); i++ {
-			s.push("array", fmt.Sprintf("[%d]", i))
+			if plain && plainEqual(x.Index(i), y.Index(i)) {
+				continue
+			}
+			s.push(Step{Kind: IndexStep, Type: x.Type(), Index: i})
 			s.deepValueEqual(x.Index(i), y.Index(i), depth+1)
 			s.pop()
-			if len(s.result) >= s.MaxDiffs {
+			if s.full() {
 				return false
 			}
 		}
@@ -200,69 +609,107 @@ func (s *compareState) deepValueEqual(x, y reflect.Value, depth int) bool {
 	case reflect.Slice:
 		if s.NilSlicesAreEmpty {
 			if x.IsNil() && y.Len() != 0 {
-				s.append("<nil slice>", y)
+				s.append(NilMismatch, x, y)
 				return false
 			} else if x.Len() != 0 && y.IsNil() {
-				s.append(x, "<nil slice>")
+				s.append(NilMismatch, x, y)
 				return false
 			}
 		} else {
 			if x.IsNil() && !y.IsNil() {
-				s.append("<nil slice>", y)
+				s.append(NilMismatch, x, y)
 				return false
 			} else if !x.IsNil() && y.IsNil() {
-				s.append(x, "<nil slice>")
+				s.append(NilMismatch, x, y)
 				return false
 			}
 		}
-		if x.Pointer() == y.Pointer() && x.Len() != y.Len() {
+		// Slices sharing a backing array are identical only if they also
+		// have the same length. A shorter slice of the same array is not.
+		if x.Pointer() == y.Pointer() && x.Len() == y.Len() {
 			return true
 		}
+		if x.Type().Elem().Kind() == reflect.Uint8 {
+			saved := s.bytes
+			s.bytes = [2]reflect.Value{x, y}
+			defer func() { s.bytes = saved }()
+		}
+		plain := s.plain(x.Type().Elem(), depth+1)
+		if plain {
+			if eq, ok := sameMemory(x, y); ok && eq {
+				return true
+			}
+		}
+		if key, ok := s.keys[x.Type().Elem()]; ok {
+			if eq, ok := s.keyedEqual(x, y, key, depth); ok {
+				return eq
+			}
+		}
+		if s.unordered(x) {
+			return s.unorderedEqual(x, y, depth)
+		}
+		if !s.CompareSlicesByIndex {
+			return s.sequenceEqual(x, y, depth)
+		}
 		n := x.Len()
 		if y.Len() > n {
 			n = y.Len()
 		}
 		for i := 0; i < n; i++ {
-			s.push("slice", fmt.Sprintf("[%d]", i))
+			if plain && i < x.Len() && i < y.Len() && plainEqual(x.Index(i), y.Index(i)) {
+				continue
+			}
+			s.push(Step{Kind: IndexStep, Type: x.Type(), Index: i})
 			if i < x.Len() {
 				if i < y.Len() {
 					s.deepValueEqual(x.Index(i), y.Index(i), depth+1)
-				} else {
-					s.append(x.Index(i), "<no value>")
+				} else if !s.ignore(x.Index(i), reflect.Value{}) {
+					s.append(MissingElement, x.Index(i), reflect.Value{})
 				}
-			} else {
-				s.append("<no value>", y.Index(i))
+			} else if !s.ignore(reflect.Value{}, y.Index(i)) {
+				s.append(ExtraElement, reflect.Value{}, y.Index(i))
 			}
 			s.pop()
-			if len(s.result) >= s.MaxDiffs {
+			if s.full() {
 				return false
 			}
 		}
 		return true
 	case reflect.Interface:
 		if x.IsNil() || y.IsNil() {
-			if x.IsNil() && !y.IsNil() {
-				s.append(fmt.Sprintf("<nil %s>", x.Type()), y)
-			} else if !x.IsNil() && y.IsNil() {
-				s.append(x, fmt.Sprintf("<nil %s>", y.Type()))
+			if x.IsNil() != y.IsNil() {
+				s.append(NilMismatch, x, y)
 			}
 			return x.IsNil() == y.IsNil()
 		}
+		s.push(Step{Kind: ElemStep, Type: x.Type()})
+		defer s.pop()
 		return s.deepValueEqual(x.Elem(), y.Elem(), depth)
 	case reflect.Ptr:
 		if x.Pointer() == y.Pointer() {
 			return true
 		}
+		if x.IsNil() || y.IsNil() {
+			s.append(NilMismatch, x, y)
+			return false
+		}
+		s.push(Step{Kind: IndirectStep, Type: x.Type()})
+		defer s.pop()
 		return s.deepValueEqual(x.Elem(), y.Elem(), depth)
 	case reflect.Struct:
-		for i, n := 0, x.NumField(); i < n; i++ {
-			if !s.CompareUnexportedFields && x.Type().Field(i).PkgPath != "" {
+		for _, f := range plan.fields {
+			if !s.CompareUnexportedFields && !f.exported {
+				continue
+			}
+			if f.ignored && len(s.overridePaths) == 0 {
+				// Ignored regardless of the path, unless an override
+				// changes the ignored types.
 				continue
 			}
-			s.push("struct", "."+x.Type().Field(i).Name)
-			s.deepValueEqual(x.Field(i), y.Field(i), depth+1)
+			s.push(Step{Kind: FieldStep, Type: x.Type(), Name: f.name, Index: f.index})
+			s.deepValueEqual(x.Field(f.index), y.Field(f.index), depth+1)
 			s.pop()
-			if len(s.result) >= s.MaxDiffs {
+			if s.full() {
 				return false
 			}
 		}
@@ -270,47 +717,51 @@ func (s *compareState) deepValueEqual(x, y reflect.Value, depth int) bool {
 	case reflect.Map:
 		if s.NilMapsAreEmpty {
 			if x.IsNil() && y.Len() != 0 {
-				s.append("<nil map>", y)
+				s.append(NilMismatch, x, y)
 				return false
 			} else if x.Len() != 0 && y.IsNil() {
-				s.append(x, "<nil map>")
+				s.append(NilMismatch, x, y)
 				return false
 			}
 		} else {
 			if x.IsNil() && !y.IsNil() {
-				s.append("<nil map>", y)
+				s.append(NilMismatch, x, y)
 				return false
 			} else if !x.IsNil() && y.IsNil() {
-				s.append(x, "<nil map>")
+				s.append(NilMismatch, x, y)
 				return false
 			}
 		}
 		if x.Pointer() == y.Pointer() {
 			return true
 		}
+		if s.DeepMapKeys {
+			return s.deepKeysEqual(x, y, depth)
+		}
 
-		for _, k := range x.MapKeys() {
-			s.push("map", fmt.Sprintf("[%v]", k))
-			if y.MapIndex(k).IsValid() {
-				s.deepValueEqual(x.MapIndex(k), y.MapIndex(k), depth+1)
-			} else if x.MapIndex(k).IsValid() {
-				s.append(x.MapIndex(k), "<no key>")
-			} else {
-				s.append("<invalid key>", "<no key>")
+		xkeys, xvalues := sortedEntries(x)
+		for i, k := range xkeys {
+			s.push(Step{Kind: MapKeyStep, Type: x.Type(), Key: k})
+			if yv := y.MapIndex(k); yv.IsValid() {
+				s.deepValueEqual(xvalues[i], yv, depth+1)
+			} else if !s.ignore(xvalues[i], reflect.Value{}) {
+				s.append(MissingKey, xvalues[i], reflect.Value{})
 			}
 			s.pop()
-			if len(s.result) >= s.MaxDiffs {
+			if s.full() {
 				return false
 			}
 		}
-		for _, k := range y.MapKeys() {
+		for _, k := range sortedKeys(y) {
 			if x.MapIndex(k).IsValid() {
 				continue
 			}
-			s.push("map", fmt.Sprintf("[%v]", k))
-			s.append("<no key>", y.MapIndex(k))
+			s.push(Step{Kind: MapKeyStep, Type: y.Type(), Key: k})
+			if !s.ignore(reflect.Value{}, y.MapIndex(k)) {
+				s.append(ExtraKey, reflect.Value{}, y.MapIndex(k))
+			}
 			s.pop()
-			if len(s.result) >= s.MaxDiffs {
+			if s.full() {
 				return false
 			}
 		}
@@ -318,90 +769,61 @@ func (s *compareState) deepValueEqual(x, y reflect.Value, depth int) bool {
 		return true
 	case reflect.Func:
 		if !x.IsNil() || !y.IsNil() {
-			s.append(x, y)
+			s.append(ValueMismatch, x, y)
 			return false
 		}
 		return true
 	case reflect.Bool:
 		if x.Bool() != y.Bool() {
-			s.append(x.Bool(), y.Bool())
+			s.append(ValueMismatch, x, y)
 			return false
 		}
 		return true
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if x.Int() != y.Int() {
-			s.append(x.Int(), y.Int())
+			s.append(ValueMismatch, x, y)
 			return false
 		}
 		return true
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		if x.Uint() != y.Uint() {
-			s.append(x.Uint(), y.Uint())
+			s.append(ValueMismatch, x, y)
 			return false
 		}
 		return true
 	case reflect.Float32, reflect.Float64:
+		bits := x.Type().Bits()
 		vx := x.Float()
 		vy := y.Float()
-		// Compare NaN. Both being NaN is considered equivalent.
-		if vx != vx && vy != vy {
-			return true
-		} else if vx != vx || vy != vy {
-			s.append(vx, vy)
-			return false
-		}
-		if s.FloatPrecision <= 0 {
-			if vx != vy {
-				s.append(vx, vy)
-				return false
-			}
-			return true
-		}
-		s.floatx.SetFloat64(vx)
-		s.floaty.SetFloat64(vy)
-		if s.floatx.Cmp(s.floaty) != 0 {
-			s.append(vx, vy)
+		if !s.floatEqual(vx, vy, bits) {
+			s.appendFloat(x, y, math.Abs(vx-vy))
 			return false
 		}
 		return true
 	case reflect.Complex64, reflect.Complex128:
+		bits := x.Type().Bits() / 2
 		vx := x.Complex()
 		vy := y.Complex()
-		if s.FloatPrecision <= 0 {
-			if vx != vy {
-				s.append(vx, vy)
-				return false
-			}
-			return true
-		}
-		s.floatx.SetFloat64(real(vx))
-		s.floaty.SetFloat64(real(vy))
-		if s.floatx.Cmp(s.floaty) != 0 {
-			s.append(vx, vy)
-			return false
-		}
-		s.floatx.SetFloat64(imag(vx))
-		s.floaty.SetFloat64(imag(vy))
-		if s.floatx.Cmp(s.floaty) != 0 {
-			s.append(vx, vy)
+		if !s.floatEqual(real(vx), real(vy), bits) || !s.floatEqual(imag(vx), imag(vy), bits) {
+			s.appendFloat(x, y, cmplx.Abs(vx-vy))
 			return false
 		}
 		return true
 	case reflect.String:
 		if x.String() != y.String() {
-			s.append(x.String(), y.String())
+			s.appendString(x, y)
+			return false
+		}
+		return true
+	case reflect.Chan, reflect.UnsafePointer:
+		if x.Pointer() != y.Pointer() {
+			s.append(ValueMismatch, x, y)
 			return false
 		}
 		return true
-	case reflect.Uintptr:
-		fallthrough
-	case reflect.Chan:
-		fallthrough
-	case reflect.UnsafePointer:
-		fallthrough
 	default:
 		if x.Interface() != y.Interface() {
-			s.append(x.Interface(), y.Interface())
+			s.append(ValueMismatch, x, y)
 			return false
 		}
 		return true
@@ -417,3 +839,15 @@ var Config = NewComparer()
 func Equal(x, y interface{}) []Diff {
 	return Config.Equal(x, y)
 }
+
+// EqualContext is like Equal, but uses ctx and the MaxNodes setting of the
+// global configuration to stop the comparison.
+func EqualContext(ctx context.Context, x, y interface{}) ([]Diff, error) {
+	return Config.EqualContext(ctx, x, y)
+}
+
+// Equivalent returns whether x and y are equivalent according to the global
+// configuration.
+func Equivalent(x, y interface{}) bool {
+	return Config.Equivalent(x, y)
+}
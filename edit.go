@@ -0,0 +1,147 @@
+package deep
+
+// editOp is the kind of operation within an edit script.
+type editOp int
+
+const (
+	editMatch  editOp = iota // Element x of the first sequence matches element y of the second.
+	editDelete               // Element x of the first sequence is removed.
+	editInsert               // Element y of the second sequence is added.
+)
+
+// edit is a single operation of an edit script.
+type edit struct {
+	op editOp
+	x  int
+	y  int
+}
+
+// maxEditDistance is the largest edit distance searched for by editScript.
+// The memory used by the search grows with the square of the distance.
+const maxEditDistance = 1024
+
+// editScript returns an edit script that transforms a sequence of length n
+// into a sequence of length m, where eq reports whether element i of the
+// first sequence is equal to element j of the second. The script contains a
+// minimal number of deletions and insertions, as determined by Myers' diff
+// algorithm.
+//
+// The common prefix and suffix of the sequences, which are usually most of
+// them, are not included in the script. Instead, their lengths are returned
+// as pre and suf, and the script covers elements pre through n-suf of the
+// first sequence and pre through m-suf of the second.
+//
+// If the sequences differ by more than limit edits, the differing portions are
+// instead reported as a deletion of every element followed by an insertion of
+// every element. If limit is zero or less, or greater than maxEditDistance,
+// then maxEditDistance is used.
+func editScript(n, m, limit int, eq func(i, j int) bool) (script []edit, pre, suf int) {
+	for pre < n && pre < m && eq(pre, pre) {
+		pre++
+	}
+	for suf < n-pre && suf < m-pre && eq(n-1-suf, m-1-suf) {
+		suf++
+	}
+
+	script, ok := myers(pre, n-suf, pre, m-suf, limit, eq)
+	if ok {
+		return script, pre, suf
+	}
+	script = make([]edit, 0, n+m-2*pre-2*suf)
+	for i := pre; i < n-suf; i++ {
+		script = append(script, edit{op: editDelete, x: i})
+	}
+	for j := pre; j < m-suf; j++ {
+		script = append(script, edit{op: editInsert, y: j})
+	}
+	return script, pre, suf
+}
+
+// myers returns a minimal edit script between the ranges [x0, x1) and [y0,
+// y1). Returns false if the edit distance exceeds limit, as interpreted by
+// editScript.
+func myers(x0, x1, y0, y1, limit int, eq func(i, j int) bool) ([]edit, bool) {
+	n, m := x1-x0, y1-y0
+	if n+m == 0 {
+		return nil, true
+	}
+	if limit <= 0 || limit > maxEditDistance {
+		limit = maxEditDistance
+	}
+	max := n + m
+	if max > limit {
+		max = limit
+	}
+
+	// v[k+max+1] is the furthest x reached on diagonal k. trace[d] holds the
+	// values of diagonals -d through d after d edits.
+	v := make([]int, 2*max+3)
+	off := max + 1
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[off+k-1] < v[off+k+1] {
+				x = v[off+k+1] // Insertion.
+			} else {
+				x = v[off+k-1] + 1 // Deletion.
+			}
+			y := x - k
+			for x < n && y < m && eq(x0+x, y0+y) {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+				return backtrack(trace, n, m, x0, y0), true
+			}
+		}
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+	}
+	return nil, false
+}
+
+// backtrack walks the trace produced by myers to produce an edit script.
+func backtrack(trace [][]int, n, m, x0, y0 int) []edit {
+	var script []edit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		var pk int
+		if k == -d || k != d && at(k-1) < at(k+1) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := at(pk)
+		// The point reached by the edit, from which the snake begins.
+		sx := px
+		if pk == k-1 {
+			sx++
+		}
+		for x > sx {
+			x--
+			y--
+			script = append(script, edit{op: editMatch, x: x0 + x, y: y0 + y})
+		}
+		if pk == k+1 {
+			y--
+			script = append(script, edit{op: editInsert, y: y0 + y})
+		} else {
+			x--
+			script = append(script, edit{op: editDelete, x: x0 + x})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		script = append(script, edit{op: editMatch, x: x0 + x, y: y0 + y})
+	}
+	for i, j := 0, len(script)-1; i < j; i, j = i+1, j-1 {
+		script[i], script[j] = script[j], script[i]
+	}
+	return script
+}
@@ -0,0 +1,119 @@
+package deep
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// lcsLength returns the length of the longest common subsequence of a and b.
+func lcsLength(a, b []int) int {
+	t := make([][]int, len(a)+1)
+	for i := range t {
+		t[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				t[i][j] = t[i+1][j+1] + 1
+			} else if t[i+1][j] > t[i][j+1] {
+				t[i][j] = t[i+1][j]
+			} else {
+				t[i][j] = t[i][j+1]
+			}
+		}
+	}
+	return t[0][0]
+}
+
+func checkScript(t *testing.T, a, b []int, script []edit, pre, suf int, minimal bool) {
+	t.Helper()
+	var out []int
+	matches := pre + suf
+	for k := 0; k < pre+suf; k++ {
+		i, j := k, k
+		if k >= pre {
+			i, j = len(a)-pre-suf+k, len(b)-pre-suf+k
+		}
+		if a[i] != b[j] {
+			t.Fatalf("%v -> %v: bad common element at (%d, %d)", a, b, i, j)
+		}
+	}
+	out = append(out, a[:pre]...)
+	i, j := pre, pre
+	for _, e := range script {
+		switch e.op {
+		case editMatch:
+			if e.x != i || e.y != j || a[e.x] != b[e.y] {
+				t.Fatalf("%v -> %v: bad match %+v at (%d, %d)", a, b, e, i, j)
+			}
+			out = append(out, a[e.x])
+			matches++
+			i++
+			j++
+		case editDelete:
+			if e.x != i {
+				t.Fatalf("%v -> %v: bad delete %+v at %d", a, b, e, i)
+			}
+			i++
+		case editInsert:
+			if e.y != j {
+				t.Fatalf("%v -> %v: bad insert %+v at %d", a, b, e, j)
+			}
+			out = append(out, b[e.y])
+			j++
+		}
+	}
+	if i != len(a)-suf || j != len(b)-suf {
+		t.Fatalf("%v -> %v: script ends at (%d, %d)", a, b, i, j)
+	}
+	out = append(out, a[len(a)-suf:]...)
+	for k := range out {
+		if out[k] != b[k] {
+			t.Fatalf("%v -> %v: script produces %v", a, b, out)
+		}
+	}
+	if lcs := lcsLength(a, b); minimal && matches != lcs {
+		t.Fatalf("%v -> %v: want %d matches, got %d", a, b, lcs, matches)
+	}
+}
+
+func TestEditScript(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 1000; n++ {
+		a := make([]int, r.Intn(12))
+		b := make([]int, r.Intn(12))
+		for i := range a {
+			a[i] = r.Intn(4)
+		}
+		for i := range b {
+			b[i] = r.Intn(4)
+		}
+		script, pre, suf := editScript(len(a), len(b), 0, func(i, j int) bool { return a[i] == b[j] })
+		checkScript(t, a, b, script, pre, suf, true)
+	}
+}
+
+func TestEditScriptLimit(t *testing.T) {
+	a := make([]int, maxEditDistance)
+	b := make([]int, maxEditDistance)
+	for i := range a {
+		a[i] = i
+		b[i] = -i - 1
+	}
+	a[0], b[0] = 0, 0
+	script, pre, suf := editScript(len(a), len(b), 0, func(i, j int) bool { return a[i] == b[j] })
+	checkScript(t, a, b, script, pre, suf, false)
+	if pre != 1 || script[0].op != editDelete || script[len(script)-1].op != editInsert {
+		t.Errorf("expected fallback script")
+	}
+
+	// A smaller limit is respected.
+	a, b = []int{1, 2, 3, 4}, []int{2, 3, 4, 5}
+	script, pre, suf = editScript(len(a), len(b), 2, func(i, j int) bool { return a[i] == b[j] })
+	checkScript(t, a, b, script, pre, suf, true)
+	script, pre, suf = editScript(len(a), len(b), 1, func(i, j int) bool { return a[i] == b[j] })
+	checkScript(t, a, b, script, pre, suf, false)
+	if script[0].op != editDelete || script[len(script)-1].op != editInsert {
+		t.Errorf("expected fallback script with limit")
+	}
+}
@@ -43,10 +43,23 @@ func newComparer(f ...interface{}) Comparer {
 	c := &Comparer{
 		CompareUnexportedFields: false,
 		FloatPrecision:          34,
+		FloatAbsTolerance:       0,
+		FloatRelTolerance:       0,
+		FloatULPs:               0,
 		MaxDepth:                0,
+		ReportMaxDepth:          false,
 		MaxDiffs:                10,
+		MaxNodes:                0,
 		NilMapsAreEmpty:         false,
 		NilSlicesAreEmpty:       false,
+		UseEqualMethods:         false,
+		UnorderedSlices:         false,
+		DeepMapKeys:             false,
+		CompareSlicesByIndex:    false,
+		TextDiffLength:          0,
+		TextDiffContext:         0,
+		Overrides:               nil,
+		Color:                   ColorAuto,
 	}
 	v := reflect.ValueOf(c).Elem()
 	for i := 0; i < len(f); i += 2 {
@@ -268,6 +281,298 @@ func TestEqual(t *testing.T) {
 			} else if eq == 0 && r != nil {
 				t.Errorf("[%d][%d]: want nil, got [] from (%v, %v): %v", i, j, test.y, test.x, r)
 			}
+			if r := c.Equivalent(test.x, test.y); r != (eq == 0) {
+				t.Errorf("[%d][%d]: want equivalent %t, got %t from (%v, %v)", i, j, eq == 0, r, test.x, test.y)
+			}
+		}
+	}
+}
+
+func TestSharedBacking(t *testing.T) {
+	x := []int{1, 2, 3}
+	for _, byIndex := range []bool{false, true} {
+		c := newComparer("MaxDiffs", 100, "CompareSlicesByIndex", byIndex)
+		if diffs := c.Equal(x, x[:3]); diffs != nil {
+			t.Errorf("byIndex=%t: want no diffs, got %v", byIndex, diffs)
+		}
+		want := []string{"slice[2]: 3 != <no value>"}
+		if got := diffStrings(c.Equal(x, x[:2])); !reflect.DeepEqual(got, want) {
+			t.Errorf("byIndex=%t: want %q, got %q", byIndex, want, got)
+		}
+		want = []string{"slice[0]: <no value> != 1", "slice[1]: <no value> != 2", "slice[2]: <no value> != 3"}
+		if got := diffStrings(c.Equal(x[:0], x)); !reflect.DeepEqual(got, want) {
+			t.Errorf("byIndex=%t: want %q, got %q", byIndex, want, got)
+		}
+		if c.Equivalent(x[:2], x) {
+			t.Errorf("byIndex=%t: want not equivalent", byIndex)
+		}
+	}
+}
+
+func TestMaxDiffs(t *testing.T) {
+	type T struct{ A, B, C int }
+	x := []T{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}, {10, 11, 12}}
+	y := []T{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}, {0, 0, 0}}
+	m := map[int]int{1: 1, 2: 2, 3: 3, 4: 4}
+	n := map[int]int{1: 0, 2: 0, 3: 0, 4: 0}
+	c := NewComparer()
+	for _, max := range []int{0, 1, 5, 12, 20} {
+		c.MaxDiffs = max
+		want := 12
+		if max > 0 && max < want {
+			want = max
+		}
+		if r := c.Equal(x, y); len(r) != want {
+			t.Errorf("slice: MaxDiffs %d: want %d diffs, got %d: %v", max, want, len(r), r)
+		}
+		want = 4
+		if max > 0 && max < want {
+			want = max
+		}
+		if r := c.Equal(m, n); len(r) != want {
+			t.Errorf("map: MaxDiffs %d: want %d diffs, got %d: %v", max, want, len(r), r)
+		}
+		if c.Equivalent(x, y) {
+			t.Errorf("MaxDiffs %d: want not equivalent", max)
+		}
+	}
+}
+
+func TestEquivalentShortCircuit(t *testing.T) {
+	type elem struct{ V int }
+	x := make([]elem, 1000)
+	y := make([]elem, 1000)
+	for i := range y {
+		y[i].V = i + 1
+	}
+	c := newComparer()
+	calls := 0
+	c.RegisterFunc(func(x, y elem) bool {
+		calls++
+		return x == y
+	})
+	if c.Equivalent(x, y) {
+		t.Fatal("want not equivalent")
+	}
+	if calls != 1 {
+		t.Errorf("want 1 comparison of elements, got %d", calls)
+	}
+	calls = 0
+	if c.Equivalent(x, y[:999]) {
+		t.Fatal("want not equivalent")
+	}
+	if calls != 1 {
+		t.Errorf("want 1 comparison of elements, got %d", calls)
+	}
+	calls = 0
+	if !c.Equivalent(x, append([]elem(nil), x...)) {
+		t.Fatal("want equivalent")
+	}
+
+	// Ignored elements may hide the remaining differences.
+	c.IgnorePaths = []string{"[*]"}
+	if !c.Equivalent(x, y[:999]) {
+		t.Errorf("want equivalent with ignored elements")
+	}
+}
+
+type benchRecord struct {
+	ID    int
+	Name  string
+	Score int64
+	Flags []uint16
+}
+
+func benchRecords(n int) []benchRecord {
+	records := make([]benchRecord, n)
+	for i := range records {
+		records[i] = benchRecord{
+			ID:    i,
+			Name:  "record",
+			Score: int64(i) * 3,
+			Flags: []uint16{uint16(i), uint16(i >> 16)},
+		}
+	}
+	return records
+}
+
+func benchInts(n int) []int {
+	ints := make([]int, n)
+	for i := range ints {
+		ints[i] = i
+	}
+	return ints
+}
+
+func BenchmarkEqual(b *testing.B) {
+	const n = 100000
+	records := func(diff bool) (x, y []benchRecord) {
+		x, y = benchRecords(n), benchRecords(n)
+		if diff {
+			y[n/2].Score++
+		}
+		return x, y
+	}
+	allDiff := func() (x, y []benchRecord) {
+		x, y = benchRecords(n), benchRecords(n)
+		for i := range y {
+			y[i].Score++
+		}
+		return x, y
+	}
+	ints := func(diff bool) (x, y []int) {
+		x, y = benchInts(n), benchInts(n)
+		if diff {
+			y[n/2]++
+		}
+		return x, y
+	}
+	var array [4096]byte
+	run := func(name string, x, y interface{}) {
+		b.Run(name, func(b *testing.B) {
+			c := NewComparer()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.Equal(x, y)
+			}
+		})
+		b.Run(name+"/Equivalent", func(b *testing.B) {
+			c := NewComparer()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.Equivalent(x, y)
+			}
+		})
+	}
+	x, y := records(false)
+	run("Records", x, y)
+	x, y = records(true)
+	run("RecordsDiff", x, y)
+	x, y = allDiff()
+	run("RecordsAllDiff", x, y)
+	xi, yi := ints(false)
+	run("Ints", xi, yi)
+	xi, yi = ints(true)
+	run("IntsDiff", xi, yi)
+	run("ByteArray", &array, &[4096]byte{})
+}
+
+type benchConfig struct {
+	Name     string
+	Enabled  bool
+	Retries  int
+	Timeout  int64
+	Weight   float64
+	Mode     parity
+	Labels   map[string]string
+	Children []benchConfig
+	Parent   *benchConfig
+	internal int
+}
+
+func BenchmarkEqualStruct(b *testing.B) {
+	config := func() *benchConfig {
+		c := &benchConfig{
+			Name:    "root",
+			Enabled: true,
+			Retries: 3,
+			Timeout: 30,
+			Weight:  0.5,
+			Mode:    1,
+			Labels:  map[string]string{"a": "1", "b": "2"},
+		}
+		for i := 0; i < 4; i++ {
+			c.Children = append(c.Children, benchConfig{Name: "child", Retries: i, Mode: parity(i)})
+		}
+		return c
+	}
+	x, y := config(), config()
+	for _, methods := range []bool{false, true} {
+		name := "Fields"
+		if methods {
+			name = "EqualMethods"
+		}
+		b.Run(name, func(b *testing.B) {
+			c := NewComparer()
+			c.UseEqualMethods = methods
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.Equal(x, y)
+			}
+		})
+	}
+}
+
+func TestReportMaxDepth(t *testing.T) {
+	type inner struct{ A, B int }
+	type outer struct {
+		Name  string
+		Inner inner
+		List  []inner
+	}
+	x := outer{Name: "x", Inner: inner{1, 2}, List: []inner{{1, 2}}}
+	y := outer{Name: "y", Inner: inner{1, 3}, List: []inner{{1, 2}, {3, 4}}}
+
+	c := newComparer("MaxDepth", 1)
+	want := []string{"struct.Name: x != y", "struct.List[1]: <no value> != {3 4}"}
+	if got := diffStrings(c.Equal(x, y)); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	c.ReportMaxDepth = true
+	diffs := c.Equal(x, y)
+	want = []string{
+		"struct.Name: x != y",
+		"struct.Inner.A: not compared beyond MaxDepth",
+		"struct.Inner.B: not compared beyond MaxDepth",
+		"struct.List[0]: not compared beyond MaxDepth",
+		"struct.List[1]: <no value> != {3 4}",
+	}
+	if got := diffStrings(diffs); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if diffs[1].Kind != DepthExceeded || diffs[1].Left.Int() != 1 || diffs[1].Right.Int() != 1 {
+		t.Errorf("want DepthExceeded with values, got %#v", diffs[1])
+	}
+	if c.Equivalent(x, x) {
+		t.Errorf("want values below MaxDepth not equivalent")
+	}
+	if _, err := NewPatch(diffs); err == nil {
+		t.Errorf("want error patching DepthExceeded")
+	}
+
+	c.UnorderedSlices = true
+	if got := diffStrings(c.Equal(x, y)); !reflect.DeepEqual(got, want) {
+		t.Errorf("unordered: want %q, got %q", want, got)
+	}
+
+	c.IgnorePaths = []string{".Inner", ".List"}
+	want = []string{"struct.Name: x != y"}
+	if got := diffStrings(c.Equal(x, y)); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestDiffInterface(t *testing.T) {
+	type inner struct{ v int }
+	type outer struct {
+		a inner
+		s []int
+		m map[string]int
+	}
+	x := outer{a: inner{1}, s: []int{1}, m: map[string]int{"k": 1}}
+	y := outer{a: inner{2}, s: []int{2}, m: map[string]int{"k": 2}}
+	diffs := newComparer("CompareUnexportedFields", true).Equal(x, y)
+	if len(diffs) != 3 {
+		t.Fatalf("want 3 diffs, got %v", diffs)
+	}
+	for _, d := range diffs[:2] {
+		if !d.Left.CanInterface() || !d.Right.CanInterface() {
+			t.Errorf("%s: want values usable with Interface", d)
+			continue
+		}
+		if d.Left.Interface() != 1 || d.Right.Interface() != 2 {
+			t.Errorf("%s: want 1 and 2, got %v and %v", d, d.Left.Interface(), d.Right.Interface())
 		}
 	}
 }
@@ -0,0 +1,78 @@
+package deep
+
+import (
+	"math"
+	"reflect"
+)
+
+// floatEqual returns whether floats x and y are equivalent according to the
+// configured tolerances. bits is the size of the type of the floats, which is
+// either 32 or 64. NaN is equivalent to NaN, and an infinity is equivalent
+// only to itself.
+func (s *compareState) floatEqual(x, y float64, bits int) bool {
+	if x == y {
+		return true
+	}
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return math.IsNaN(x) && math.IsNaN(y)
+	}
+	// Infinities are equivalent only to themselves, which is handled above.
+	// Otherwise, an infinite difference would be within a relative tolerance.
+	if math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return false
+	}
+	if s.FloatPrecision > 0 {
+		s.floatx.SetFloat64(x)
+		s.floaty.SetFloat64(y)
+		if s.floatx.Cmp(s.floaty) == 0 {
+			return true
+		}
+	}
+	delta := math.Abs(x - y)
+	if s.FloatAbsTolerance > 0 && delta <= s.FloatAbsTolerance {
+		return true
+	}
+	if s.FloatRelTolerance > 0 && delta <= s.FloatRelTolerance*math.Max(math.Abs(x), math.Abs(y)) {
+		return true
+	}
+	if s.FloatULPs > 0 && ulpDistance(x, y, bits) <= uint64(s.FloatULPs) {
+		return true
+	}
+	return false
+}
+
+// appendFloat appends a ValueMismatch between floating-point or complex
+// numbers x and y, which differ by delta.
+func (s *compareState) appendFloat(x, y reflect.Value, delta float64) {
+	s.append(ValueMismatch, x, y)
+	if !s.quiet {
+		s.result[len(s.result)-1].Delta = delta
+	}
+}
+
+// ulpDistance returns the number of representable values between x and y,
+// which are floats of the given size in bits.
+func ulpDistance(x, y float64, bits int) uint64 {
+	var a, b int64
+	if bits == 32 {
+		a = orderedBits(int64(int32(math.Float32bits(float32(x)))), math.MinInt32)
+		b = orderedBits(int64(int32(math.Float32bits(float32(y)))), math.MinInt32)
+	} else {
+		a = orderedBits(int64(math.Float64bits(x)), math.MinInt64)
+		b = orderedBits(int64(math.Float64bits(y)), math.MinInt64)
+	}
+	if a > b {
+		return uint64(a) - uint64(b)
+	}
+	return uint64(b) - uint64(a)
+}
+
+// orderedBits maps the bits of a float, interpreted as a signed integer, to
+// an integer that is ordered in the same way as the float. min is the
+// smallest integer of the size of the float.
+func orderedBits(i, min int64) int64 {
+	if i < 0 {
+		return min - i
+	}
+	return i
+}
@@ -0,0 +1,93 @@
+package deep
+
+import (
+	"math"
+	"testing"
+)
+
+type floatTest struct {
+	x, y  interface{}
+	equal bool
+}
+
+func TestFloatTolerances(t *testing.T) {
+	tests := []struct {
+		c     Comparer
+		tests []floatTest
+	}{
+		{newComparer("FloatPrecision", 0, "FloatAbsTolerance", 0.01), []floatTest{
+			{0.49999, 0.50001, true},
+			{1.0, 1.005, true},
+			{1.0, 1.02, false},
+			{float32(100), float32(100.005), true},
+			{complex(1, 1), complex(1.005, 0.995), true},
+			{complex(1, 1), complex(1, 1.1), false},
+			{math.Inf(1), math.Inf(1), true},
+			{math.Inf(1), math.MaxFloat64, false},
+			{math.NaN(), 0.0, false},
+		}},
+		{newComparer("FloatPrecision", 0, "FloatRelTolerance", 1e-3), []floatTest{
+			{1000.0, 1000.9, true},
+			{1000.0, 1001.1, false},
+			{1e-9, 1.0009e-9, true},
+			{1e-9, 1.1e-9, false},
+			{0.0, 1e-300, false},
+			{math.Inf(1), math.Inf(1), true},
+			{math.Inf(1), 1.0, false},
+			{1.0, math.Inf(-1), false},
+			{math.Inf(1), math.Inf(-1), false},
+			{complex(math.Inf(1), 0), complex(1, 0), false},
+		}},
+		{newComparer("FloatPrecision", 0, "FloatULPs", 2), []floatTest{
+			{1.0, math.Nextafter(1, 2), true},
+			{1.0, math.Nextafter(math.Nextafter(1, 0), 0), true},
+			{1.0, math.Nextafter(math.Nextafter(math.Nextafter(1, 2), 2), 2), false},
+			{0.0, math.Copysign(0, -1), true},
+			{math.SmallestNonzeroFloat64, -math.SmallestNonzeroFloat64, true},
+			{float32(1), math.Nextafter32(math.Nextafter32(1, 2), 2), true},
+			{float32(1), float32(1.0000001), true},
+			{float32(1), float32(1.000001), false},
+			{complex64(complex(1, 1)), complex64(complex(1, math.Nextafter32(1, 2))), true},
+			{math.Inf(1), math.MaxFloat64, false},
+			{float32(math.Inf(-1)), float32(-math.MaxFloat32), false},
+		}},
+		// Tolerances only loosen the comparison.
+		{newComparer("FloatPrecision", 4, "FloatAbsTolerance", 1e-9), []floatTest{
+			{0.5, 0.52, true},
+			{0.5, 0.5000000001, true},
+			{0.5, 0.55, false},
+		}},
+	}
+	for i, tt := range tests {
+		for j, test := range tt.tests {
+			diffs := tt.c.Equal(test.x, test.y)
+			if test.equal && diffs != nil {
+				t.Errorf("[%d][%d]: want equal, got %v", i, j, diffs)
+			} else if !test.equal && len(diffs) != 1 {
+				t.Errorf("[%d][%d]: want 1 diff, got %v", i, j, diffs)
+			}
+			if eq := tt.c.Equivalent(test.x, test.y); eq != test.equal {
+				t.Errorf("[%d][%d]: want Equivalent %t, got %t", i, j, test.equal, eq)
+			}
+		}
+	}
+}
+
+func TestFloatDelta(t *testing.T) {
+	c := newComparer("FloatPrecision", 0)
+	diffs := c.Equal(1.5, 1.25)
+	if len(diffs) != 1 || diffs[0].Delta != 0.25 {
+		t.Fatalf("want delta 0.25, got %v", diffs)
+	}
+	if s := diffs[0].String(); s != "1.5 != 1.25 (delta 0.25)" {
+		t.Errorf("unexpected string %q", s)
+	}
+	diffs = c.Equal(complex(0, 0), complex(3, 4))
+	if len(diffs) != 1 || diffs[0].Delta != 5 {
+		t.Errorf("want delta 5, got %v", diffs)
+	}
+	diffs = c.Equal(math.NaN(), 1.0)
+	if s := diffs[0].String(); s != "NaN != 1" {
+		t.Errorf("unexpected string %q", s)
+	}
+}
@@ -0,0 +1,170 @@
+package deep
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+var (
+	boolType  = reflect.TypeOf(false)
+	diffsType = reflect.TypeOf([]Diff(nil))
+)
+
+// RegisterFunc registers a function used to compare values of a particular
+// type. The function must have one of the following forms:
+//
+//	func(x, y T) bool
+//	func(x, y T) []Diff
+//
+// Values of type T are passed to the function instead of being compared
+// normally. A function that returns a bool reports whether x and y are
+// equivalent; if they are not, a single ValueMismatch is produced at the
+// location of the values. A function that returns a slice of Diffs returns the
+// differences between x and y, with paths relative to x and y.
+//
+// Registering a function for a type replaces any function previously
+// registered for the type. Passing a nil function of a valid form removes the
+// function registered for T. RegisterFunc panics if fn does not have a valid
+// form.
+//
+// Values that cannot be exported, such as those obtained through unexported
+// fields of values within maps or interfaces, are compared normally.
+//
+// The registered functions are shared by copies of the Comparer, but
+// registering a function affects only c.
+func (c *Comparer) RegisterFunc(fn interface{}) {
+	if fn == nil {
+		panic("deep: RegisterFunc: nil function")
+	}
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func ||
+		t.NumIn() != 2 ||
+		t.In(0) != t.In(1) ||
+		t.NumOut() != 1 ||
+		(t.Out(0) != boolType && t.Out(0) != diffsType) ||
+		t.IsVariadic() {
+		panic(fmt.Sprintf("deep: RegisterFunc: invalid function type %s", t))
+	}
+	// Copy on write, so that copies of the Comparer are not affected.
+	funcs := make(map[reflect.Type]reflect.Value, len(c.funcs)+1)
+	for k, f := range c.funcs {
+		funcs[k] = f
+	}
+	if v.IsNil() {
+		delete(funcs, t.In(0))
+	} else {
+		funcs[t.In(0)] = v
+	}
+	c.funcs = funcs
+}
+
+// callFunc compares x and y with a registered function. Returns whether the
+// values are equivalent, and whether the function could be called. Values
+// that cannot be exported are not passed to the function.
+func (s *compareState) callFunc(fn reflect.Value, x, y reflect.Value) (eq, ok bool) {
+	if x, ok = exportValue(x); !ok {
+		return false, false
+	}
+	if y, ok = exportValue(y); !ok {
+		return false, false
+	}
+	out := fn.Call([]reflect.Value{x, y})[0]
+	if out.Kind() == reflect.Bool {
+		if !out.Bool() {
+			s.append(ValueMismatch, x, y)
+			return false, true
+		}
+		return true, true
+	}
+	diffs := out.Interface().([]Diff)
+	for _, d := range diffs {
+		if s.full() {
+			break
+		}
+		s.ndiffs++
+		if s.quiet {
+			continue
+		}
+		path := make(Path, 0, len(s.stack)+len(d.Path))
+		path = append(path, s.stack...)
+		d.Path = append(path, d.Path...)
+		s.result = append(s.result, d)
+	}
+	return len(diffs) == 0, true
+}
+
+// exportValue returns a value that can be used with Interface and Call, even
+// if v was obtained through unexported fields. Returns false if v cannot be
+// exported.
+func exportValue(v reflect.Value) (reflect.Value, bool) {
+	if v.CanInterface() {
+		return v, true
+	}
+	if !v.CanAddr() {
+		return v, false
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem(), true
+}
+
+// addressable returns an addressable copy of v.
+func addressable(v reflect.Value) reflect.Value {
+	a := reflect.New(v.Type()).Elem()
+	a.Set(v)
+	return a
+}
+
+// equalMethod returns the Equal method of type t, which has the form
+// "Equal(T) bool". ptr is true if the method is defined on *T rather than T.
+// Returns false if t has no such method.
+func equalMethod(t reflect.Type) (m reflect.Method, ptr bool, ok bool) {
+	if t.Kind() == reflect.Interface {
+		return m, false, false
+	}
+	valid := func(m reflect.Method) bool {
+		return m.Type.NumIn() == 2 &&
+			m.Type.In(1) == t &&
+			m.Type.NumOut() == 1 &&
+			m.Type.Out(0) == boolType
+	}
+	if m, ok = t.MethodByName("Equal"); ok && valid(m) {
+		return m, false, true
+	}
+	if m, ok = reflect.PtrTo(t).MethodByName("Equal"); ok && valid(m) {
+		return m, true, true
+	}
+	return m, false, false
+}
+
+// callEqualMethod compares x and y by calling the Equal method of x, as found
+// in the plan of their type. Returns whether the values are equivalent, and
+// whether the method could be called.
+func (s *compareState) callEqualMethod(plan *typePlan, x, y reflect.Value) (eq, ok bool) {
+	if !plan.hasEqual {
+		return false, false
+	}
+	m, ptr := plan.equal, plan.equalPtr
+	if x.Kind() == reflect.Ptr && (x.IsNil() || y.IsNil()) {
+		// Let nil pointers be handled normally rather than risking a panic.
+		return false, false
+	}
+	if x, ok = exportValue(x); !ok {
+		return false, false
+	}
+	if y, ok = exportValue(y); !ok {
+		return false, false
+	}
+	recv := x
+	if ptr {
+		if !recv.CanAddr() {
+			recv = addressable(recv)
+		}
+		recv = recv.Addr()
+	}
+	if !m.Func.Call([]reflect.Value{recv, y})[0].Bool() {
+		s.append(ValueMismatch, x, y)
+		return false, true
+	}
+	return true, true
+}
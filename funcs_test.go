@@ -0,0 +1,148 @@
+package deep
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type money struct {
+	Cents    int
+	Currency string
+}
+
+type normalizedID string
+
+type funcHolder struct {
+	Amount money
+	ID     normalizedID
+	id     normalizedID
+}
+
+func TestRegisterFunc(t *testing.T) {
+	c := newComparer("CompareUnexportedFields", true)
+	c.RegisterFunc(func(x, y normalizedID) bool {
+		return strings.EqualFold(string(x), string(y))
+	})
+	c.RegisterFunc(func(x, y money) []Diff {
+		if x.Currency != y.Currency {
+			return []Diff{{Kind: ValueMismatch, Path: Path{{Kind: FieldStep, Name: "Currency", Index: 1}}}}
+		}
+		return nil
+	})
+
+	x := funcHolder{Amount: money{1, "USD"}, ID: "abc", id: "def"}
+	if diffs := c.Equal(x, funcHolder{Amount: money{2, "USD"}, ID: "ABC", id: "DEF"}); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+
+	diffs := c.Equal(x, funcHolder{Amount: money{1, "EUR"}, ID: "abd", id: "def"})
+	if len(diffs) != 2 {
+		t.Fatalf("want 2 diffs, got %d: %v", len(diffs), diffs)
+	}
+	if s := diffs[0].Path.String(); s != "struct.Amount.Currency" {
+		t.Errorf("want path struct.Amount.Currency, got %s", s)
+	}
+	if s := diffs[1].String(); s != "struct.ID: abc != abd" {
+		t.Errorf("unexpected diff %s", s)
+	}
+
+	// Registered functions do not affect other copies.
+	if diffs := newComparer().Equal(normalizedID("a"), normalizedID("A")); len(diffs) != 1 {
+		t.Errorf("want 1 diff, got %d: %v", len(diffs), diffs)
+	}
+	d := c
+	d.RegisterFunc((func(x, y normalizedID) bool)(nil))
+	if diffs := d.Equal(normalizedID("a"), normalizedID("A")); len(diffs) != 1 {
+		t.Errorf("want 1 diff after removal, got %d: %v", len(diffs), diffs)
+	}
+	if diffs := c.Equal(normalizedID("a"), normalizedID("A")); diffs != nil {
+		t.Errorf("want no diffs from original, got %v", diffs)
+	}
+}
+
+func TestRegisterFuncInvalid(t *testing.T) {
+	for i, fn := range []interface{}{
+		nil,
+		1,
+		func(x int) bool { return true },
+		func(x, y int) {},
+		func(x int, y uint) bool { return true },
+		func(x, y int) int { return 0 },
+		func(x int, y ...int) bool { return true },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("[%d]: expected panic", i)
+				}
+			}()
+			var c Comparer
+			c.RegisterFunc(fn)
+		}()
+	}
+}
+
+type ptrEqual struct {
+	Value int
+	Noise int
+}
+
+func (p *ptrEqual) Equal(q ptrEqual) bool {
+	return p.Value == q.Value
+}
+
+type timeHolder struct {
+	Time  time.Time
+	IP    net.IP
+	Ptr   ptrEqual
+	inner time.Time
+}
+
+func TestUseEqualMethods(t *testing.T) {
+	now := time.Now()
+	x := timeHolder{
+		Time:  now,
+		IP:    net.IPv4(127, 0, 0, 1),
+		Ptr:   ptrEqual{Value: 1, Noise: 1},
+		inner: now,
+	}
+	y := timeHolder{
+		Time:  now.Round(0).In(time.FixedZone("X", 3600)),
+		IP:    net.ParseIP("::ffff:127.0.0.1").To4(),
+		Ptr:   ptrEqual{Value: 1, Noise: 2},
+		inner: now.Round(0),
+	}
+
+	c := newComparer("CompareUnexportedFields", true)
+	if diffs := c.Equal(x, y); len(diffs) == 0 {
+		t.Errorf("want diffs without UseEqualMethods")
+	}
+	c.UseEqualMethods = true
+	if diffs := c.Equal(x, y); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+	if diffs := c.Equal(&x, &y); diffs != nil {
+		t.Errorf("want no diffs through pointers, got %v", diffs)
+	}
+
+	y.Time = y.Time.Add(time.Second)
+	y.Ptr.Value = 2
+	diffs := c.Equal(x, y)
+	if len(diffs) != 2 {
+		t.Fatalf("want 2 diffs, got %d: %v", len(diffs), diffs)
+	}
+	if s := diffs[0].Path.String(); s != "struct.Time" {
+		t.Errorf("want path struct.Time, got %s", s)
+	}
+	if s := diffs[1].Path.String(); s != "struct.Ptr" {
+		t.Errorf("want path struct.Ptr, got %s", s)
+	}
+
+	// Registered functions take precedence.
+	c.RegisterFunc(func(x, y time.Time) bool { return true })
+	if diffs := c.Equal(x.Time, y.Time); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+}
@@ -0,0 +1,33 @@
+package deep
+
+// EqualOf is like Equal, but requires x and y to have the same static type,
+// so that comparing values of different types is caught at compile time. If
+// T is an interface type, the dynamic types of x and y may still differ.
+func EqualOf[T any](x, y T) []Diff {
+	return Config.Equal(x, y)
+}
+
+// EqualUsing is like EqualOf, but compares x and y using c.
+func EqualUsing[T any](c Comparer, x, y T) []Diff {
+	return c.Equal(x, y)
+}
+
+// CompareWith registers fn to compare values of type T with c. It is a typed
+// form of RegisterFunc, and has the same behavior. Passing a nil function
+// removes the function registered for T.
+func CompareWith[T any](c *Comparer, fn func(x, y T) bool) {
+	c.RegisterFunc(fn)
+}
+
+// DiffWith registers fn to produce the differences between values of type T
+// with c. It is a typed form of RegisterFunc, and has the same behavior.
+// Passing a nil function removes the function registered for T.
+func DiffWith[T any](c *Comparer, fn func(x, y T) []Diff) {
+	c.RegisterFunc(fn)
+}
+
+// KeyWith registers fn to compute the identity key of elements of type T
+// with c. It is a typed form of RegisterKey, and has the same behavior.
+func KeyWith[T any, K comparable](c *Comparer, fn func(T) K) {
+	c.RegisterKey(fn)
+}
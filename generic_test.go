@@ -0,0 +1,54 @@
+package deep
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEqualOf(t *testing.T) {
+	if diffs := EqualOf(basic{1, 2}, basic{1, 2}); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+	if diffs := EqualOf([]int{1}, []int{2}); len(diffs) != 1 || diffs[0].Kind != ValueMismatch {
+		t.Errorf("want value mismatch, got %v", diffs)
+	}
+	// Dynamic types of an interface type may still differ.
+	if diffs := EqualOf[interface{}](1, "a"); len(diffs) != 1 || diffs[0].Kind != TypeMismatch {
+		t.Errorf("want type mismatch, got %v", diffs)
+	}
+
+	c := newComparer("FloatPrecision", 0)
+	if diffs := EqualUsing(c, 1.0, 1.0000001); len(diffs) != 1 {
+		t.Errorf("want 1 diff, got %v", diffs)
+	}
+}
+
+func TestCompareWith(t *testing.T) {
+	c := newComparer("MaxDiffs", 100)
+	CompareWith(&c, func(x, y string) bool { return strings.EqualFold(x, y) })
+	if diffs := EqualUsing(c, []string{"a", "B"}, []string{"A", "b"}); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+	CompareWith[string](&c, nil)
+	if diffs := EqualUsing(c, "a", "A"); len(diffs) != 1 {
+		t.Errorf("want diff after removal, got %v", diffs)
+	}
+
+	DiffWith(&c, func(x, y basic) []Diff {
+		if x.X == y.X {
+			return nil
+		}
+		return []Diff{{Kind: ValueMismatch, Left: reflect.ValueOf(x.X), Right: reflect.ValueOf(y.X)}}
+	})
+	if diffs := EqualUsing(c, basic{1, 2}, basic{1, 3}); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+
+	KeyWith(&c, func(r keyedRecord) int { return r.ID })
+	x := []keyedRecord{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	y := []keyedRecord{{ID: 2, Name: "b"}, {ID: 1, Name: "a"}}
+	if diffs := EqualUsing(c, x, y); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/anaminus/deep
+
+go 1.18
@@ -0,0 +1,394 @@
+package deep
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// jsonOp is a single operation of a JSON Patch document.
+type jsonOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch returns a JSON Patch document, as described by RFC 6902, that,
+// when applied to the JSON encoding of the left value of the comparison that
+// produced diffs, causes it to become the JSON encoding of the right value.
+// As with NewPatch, diffs must describe every difference between the values,
+// in the order produced by the Comparer.
+//
+// Locations are mapped to JSON Pointers as the encoding/json package would
+// encode the values:
+//
+//   - Struct fields are named by their json tag, if present. Fields of
+//     embedded structs are promoted into the outer object.
+//   - Differences within unexported fields, and fields tagged with "-", are
+//     not encoded, and so are omitted from the document.
+//   - Elements of arrays and slices are selected by their index.
+//   - Map keys are strings, integers, or implementations of
+//     encoding.TextMarshaler.
+//   - Pointers and interfaces are transparent.
+//
+// A difference of a field tagged with "omitempty" adds the field if it is
+// empty in the left value, and removes it if it is empty in the right value.
+// A difference of an embedded struct as a whole, such as a nil and a non-nil
+// embedded pointer, changes each of its promoted fields. Byte slices, being
+// encoded as strings, are replaced as a whole. Values of fields with the
+// "string" option are encoded as strings.
+//
+// An error is returned if a difference is located within a value that
+// implements json.Marshaler or encoding.TextMarshaler, or if a value cannot be
+// encoded.
+func JSONPatch(diffs []Diff) ([]byte, error) {
+	p, err := NewPatch(diffs)
+	if err != nil {
+		return nil, err
+	}
+	doc := make([]jsonOp, 0, len(p))
+	// The pointer of the byte slice replaced by the previous operation, if
+	// any.
+	replacing, replaced := false, ""
+	for i, op := range p {
+		left := diffs[i].Left
+		bytes := false
+		if k := byteSliceStep(op.Path); k >= 0 {
+			if !diffs[i].bytes[1].IsValid() {
+				return nil, fmt.Errorf("deep: JSONPatch: byte slice containing %s is unknown", op.Path)
+			}
+			op = Op{Kind: SetOp, Path: op.Path[:k], Value: diffs[i].bytes[1]}
+			left = diffs[i].bytes[0]
+			bytes = true
+		}
+		ptr, omit, err := op.Path.jsonPointer()
+		if err != nil {
+			return nil, err
+		}
+		if omit {
+			continue
+		}
+		if bytes && replacing && ptr == replaced {
+			// Covered by replacing the whole slice.
+			continue
+		}
+		replacing, replaced = bytes, ptr
+
+		var field reflect.StructField
+		n := len(op.Path)
+		if n > 0 && op.Path[n-1].Kind == FieldStep {
+			field = op.Path[n-1].Type.Field(op.Path[n-1].Index)
+		}
+		if _, promoted, _ := jsonName(field); promoted {
+			ops, err := promotedOps(ptr, field.Type, left, op.Value)
+			if err != nil {
+				return nil, fmt.Errorf("deep: JSONPatch: value at %s: %w", op.Path, err)
+			}
+			doc = append(doc, ops...)
+			continue
+		}
+
+		jop := jsonOp{Path: ptr}
+		switch op.Kind {
+		case SetOp:
+			jop.Op = "replace"
+			if hasOption(field, "omitempty") {
+				switch {
+				case isEmptyValue(op.Value) && isEmptyValue(left):
+					// Absent from both encodings.
+					continue
+				case isEmptyValue(op.Value):
+					jop.Op = "remove"
+				case isEmptyValue(left):
+					jop.Op = "add"
+				}
+			}
+		case InsertOp:
+			jop.Op = "add"
+		case DeleteOp:
+			jop.Op = "remove"
+		}
+		if jop.Op != "remove" {
+			if jop.Value, err = marshalValue(op.Value, quotedPath(op.Path)); err != nil {
+				return nil, fmt.Errorf("deep: JSONPatch: value at %s: %w", op.Path, err)
+			}
+		}
+		doc = append(doc, jop)
+	}
+	return json.Marshal(doc)
+}
+
+// promotedOps returns the operations that change the fields of an embedded
+// struct of type t, which are promoted into the object located at ptr, from
+// their values within x to their values within y. The fields of an invalid
+// value or a nil pointer are absent.
+func promotedOps(ptr string, t reflect.Type, x, y reflect.Value) ([]jsonOp, error) {
+	if t.Kind() == reflect.Ptr {
+		x, y = elem(x), elem(y)
+		t = t.Elem()
+	}
+	var ops []jsonOp
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		var fx, fy reflect.Value
+		if x.IsValid() {
+			fx = x.Field(i)
+		}
+		if y.IsValid() {
+			fy = y.Field(i)
+		}
+		name, promoted, omit := jsonName(field)
+		if omit {
+			continue
+		}
+		if promoted {
+			more, err := promotedOps(ptr, field.Type, fx, fy)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, more...)
+			continue
+		}
+		empty := hasOption(field, "omitempty")
+		inx := fx.IsValid() && !(empty && isEmptyValue(fx))
+		iny := fy.IsValid() && !(empty && isEmptyValue(fy))
+		op := jsonOp{Path: ptr + "/" + escapePointer(name)}
+		switch {
+		case inx && iny:
+			op.Op = "replace"
+		case iny:
+			op.Op = "add"
+		case inx:
+			op.Op = "remove"
+		default:
+			continue
+		}
+		if op.Op != "remove" {
+			var err error
+			if op.Value, err = marshalValue(fy, quotedField(field)); err != nil {
+				return nil, err
+			}
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// byteSliceStep returns the index of the first step of path that selects an
+// element of a slice encoded as a string, or -1 if there is none.
+func byteSliceStep(path Path) int {
+	for k, step := range path {
+		if step.Kind != IndexStep && step.Kind != KeyedStep || step.Type == nil || step.Type.Kind() != reflect.Slice {
+			continue
+		}
+		// As with encoding/json, a byte type with a custom encoding is
+		// encoded as an element of an array.
+		e := step.Type.Elem()
+		if e.Kind() == reflect.Uint8 && !reflect.PtrTo(e).Implements(jsonMarshalerType) && !reflect.PtrTo(e).Implements(textMarshalerType) {
+			return k
+		}
+	}
+	return -1
+}
+
+// marshalValue returns the JSON encoding of v. An invalid value is encoded as
+// null. If quoted is true, then a value other than null is encoded within a
+// string, as with the "string" option.
+func marshalValue(v reflect.Value, quoted bool) (json.RawMessage, error) {
+	if !v.IsValid() {
+		return json.RawMessage("null"), nil
+	}
+	v, ok := exportValue(v)
+	if !ok {
+		return nil, errors.New("value obtained through unexported field")
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil || !quoted || string(b) == "null" {
+		return b, err
+	}
+	return json.Marshal(string(b))
+}
+
+// quotedPath returns whether the value located at path is the value of a
+// field with the "string" option.
+func quotedPath(path Path) bool {
+	n := len(path)
+	for n > 0 && path[n-1].Kind == IndirectStep {
+		n--
+	}
+	if n == 0 || path[n-1].Kind != FieldStep {
+		return false
+	}
+	return quotedField(path[n-1].Type.Field(path[n-1].Index))
+}
+
+// quotedField returns whether the value of field is encoded within a string,
+// because it has the "string" option and a type to which the option applies.
+func quotedField(field reflect.StructField) bool {
+	if !hasOption(field, "string") {
+		return false
+	}
+	t := field.Type
+	if t.Name() == "" && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	}
+	return false
+}
+
+// JSONPointer returns the JSON Pointer, as described by RFC 6901, that
+// locates the same value as the path within the JSON encoding of the root
+// value. Steps are mapped as described by JSONPatch. An error is returned if
+// the path passes through a value that is not encoded, or a value that
+// implements json.Marshaler or encoding.TextMarshaler.
+func (p Path) JSONPointer() (string, error) {
+	ptr, omit, err := p.jsonPointer()
+	if err != nil {
+		return "", err
+	}
+	if omit {
+		return "", fmt.Errorf("deep: value at %s is not encoded", p)
+	}
+	return ptr, nil
+}
+
+// jsonPointer returns the JSON Pointer of the path, and whether the path
+// passes through a field that is not encoded.
+func (p Path) jsonPointer() (ptr string, omit bool, err error) {
+	var b strings.Builder
+	for _, step := range p {
+		if step.Type != nil && (step.Type.Implements(jsonMarshalerType) || step.Type.Implements(textMarshalerType)) {
+			return "", false, fmt.Errorf("deep: %s is located within %s, which has a custom encoding", p, step.Type)
+		}
+		switch step.Kind {
+		case FieldStep:
+			name, promoted, omit := jsonName(step.Type.Field(step.Index))
+			if omit {
+				return "", true, nil
+			}
+			if promoted {
+				continue
+			}
+			b.WriteString("/" + escapePointer(name))
+		case IndexStep, KeyedStep:
+			b.WriteString("/" + strconv.Itoa(step.Index))
+		case MapKeyStep:
+			name, err := jsonKey(step.Key)
+			if err != nil {
+				return "", false, fmt.Errorf("deep: key at %s: %w", p, err)
+			}
+			b.WriteString("/" + escapePointer(name))
+		}
+	}
+	return b.String(), false, nil
+}
+
+// escapePointer escapes a reference token of a JSON Pointer.
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// jsonKey returns the name of the object member encoded for map key k.
+func jsonKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if k.Type().Implements(textMarshalerType) {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		text, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported key type %s", k.Type())
+}
+
+// jsonName returns the name of the object member encoded for field. promoted
+// is true if the fields of field are instead promoted into the outer object,
+// and omit is true if field is not encoded. A zero field is neither promoted
+// nor omitted.
+func jsonName(field reflect.StructField) (name string, promoted, omit bool) {
+	tag := field.Tag.Get("json")
+	name, _ = splitTag(tag)
+	switch {
+	case field.Type == nil:
+	case tag == "-":
+		omit = true
+	case field.PkgPath != "" && !(field.Anonymous && isStructType(field.Type)):
+		omit = true
+	case field.Anonymous && name == "" && isStructType(field.Type):
+		promoted = true
+	case name == "":
+		name = field.Name
+	}
+	return name, promoted, omit
+}
+
+// splitTag splits a json tag into its name and options.
+func splitTag(tag string) (name string, opts []string) {
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
+
+// isStructType returns whether t is a struct or a pointer to a struct.
+func isStructType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// hasOption returns whether the json tag of field has the given option.
+func hasOption(field reflect.StructField, opt string) bool {
+	_, opts := splitTag(field.Tag.Get("json"))
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmptyValue returns whether v is empty, as defined by the omitempty option
+// of the encoding/json package.
+func isEmptyValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package deep
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type jsonEmbedded struct {
+	Version int `json:"version"`
+}
+
+type jsonRecord struct {
+	jsonEmbedded
+	Name    string            `json:"name"`
+	Note    string            `json:"note,omitempty"`
+	Tags    []string          `json:"tags"`
+	Attrs   map[string]int    `json:"attrs"`
+	ByID    map[int]string    `json:"by_id"`
+	Secret  string            `json:"-"`
+	Dash    string            `json:"-,"`
+	Plain   *basic            `json:"plain"`
+	When    time.Time         `json:"when"`
+	Keyed   map[string]string `json:"a/b~c"`
+	private int
+}
+
+func TestJSONPatch(t *testing.T) {
+	c := newComparer("MaxDiffs", 1000, "CompareUnexportedFields", true)
+	x := jsonRecord{
+		jsonEmbedded: jsonEmbedded{1},
+		Name:         "a",
+		Note:         "note",
+		Tags:         []string{"x", "y", "z"},
+		Attrs:        map[string]int{"k": 1, "gone": 2},
+		ByID:         map[int]string{1: "one"},
+		Secret:       "s",
+		Plain:        &basic{1, 2},
+		Keyed:        map[string]string{"~/": "a"},
+	}
+	y := jsonRecord{
+		jsonEmbedded: jsonEmbedded{2},
+		Name:         "b",
+		Tags:         []string{"y", "w"},
+		Attrs:        map[string]int{"k": 1},
+		ByID:         map[int]string{1: "uno"},
+		Secret:       "t",
+		Dash:         "d",
+		Plain:        &basic{1, 3},
+		Keyed:        map[string]string{"~/": "b"},
+	}
+	got, err := JSONPatch(c.Equal(x, y))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[` +
+		`{"op":"replace","path":"/version","value":2},` +
+		`{"op":"replace","path":"/name","value":"b"},` +
+		`{"op":"remove","path":"/note"},` +
+		`{"op":"remove","path":"/tags/0"},` +
+		`{"op":"replace","path":"/tags/1","value":"w"},` +
+		`{"op":"remove","path":"/attrs/gone"},` +
+		`{"op":"replace","path":"/by_id/1","value":"uno"},` +
+		`{"op":"replace","path":"/-","value":"d"},` +
+		`{"op":"replace","path":"/plain/Y","value":3},` +
+		`{"op":"replace","path":"/a~1b~0c/~0~1","value":"b"}` +
+		`]`
+	if string(got) != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+
+	got, err = JSONPatch(c.Equal(jsonRecord{}, jsonRecord{Note: "n"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"op":"add","path":"/note","value":"n"}]`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+
+	// Differences within a value with a custom encoding.
+	diffs := c.Equal(jsonRecord{When: time.Unix(1, 0)}, jsonRecord{When: time.Unix(2, 0)})
+	if _, err := JSONPatch(diffs); err == nil {
+		t.Errorf("want error for custom encoding, got diffs %v", diffs)
+	}
+}
+
+type jsonInner struct {
+	A int    `json:"a"`
+	B string `json:"b,omitempty"`
+}
+
+type jsonOuter struct {
+	*jsonInner
+	N     int    `json:"n"`
+	Bytes []byte `json:"bytes"`
+	Count int    `json:"count,string"`
+	Ptr   *bool  `json:"ptr,string"`
+}
+
+func TestJSONPatchEncoding(t *testing.T) {
+	c := newComparer("MaxDiffs", 1000, "CompareUnexportedFields", true)
+	yes := true
+	tests := []struct {
+		x, y jsonOuter
+		want string
+	}{
+		// Promoted fields of an embedded pointer are changed individually.
+		{
+			jsonOuter{N: 1},
+			jsonOuter{jsonInner: &jsonInner{A: 1}, N: 1},
+			`[{"op":"add","path":"/a","value":1}]`,
+		},
+		{
+			jsonOuter{jsonInner: &jsonInner{A: 1, B: "b"}, N: 1},
+			jsonOuter{N: 1},
+			`[{"op":"remove","path":"/a"},{"op":"remove","path":"/b"}]`,
+		},
+		// Byte slices are encoded as strings, so are replaced as a whole.
+		{
+			jsonOuter{Bytes: []byte("abc")},
+			jsonOuter{Bytes: []byte("xbyz")},
+			`[{"op":"replace","path":"/bytes","value":"eGJ5eg=="}]`,
+		},
+		// Values of fields with the string option are strings.
+		{
+			jsonOuter{Count: 1},
+			jsonOuter{Count: 2},
+			`[{"op":"replace","path":"/count","value":"2"}]`,
+		},
+		{
+			jsonOuter{},
+			jsonOuter{Ptr: &yes},
+			`[{"op":"replace","path":"/ptr","value":"true"}]`,
+		},
+	}
+	for i, test := range tests {
+		got, err := JSONPatch(c.Equal(test.x, test.y))
+		if err != nil {
+			t.Errorf("[%d]: %s", i, err)
+		} else if string(got) != test.want {
+			t.Errorf("[%d]: want %s, got %s", i, test.want, got)
+		}
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	typ := reflect.TypeOf(jsonRecord{})
+	tests := []struct {
+		path Path
+		want string
+		err  bool
+	}{
+		{Path{}, "", false},
+		{Path{{Kind: FieldStep, Type: typ, Index: 1}}, "/name", false},
+		{Path{{Kind: FieldStep, Type: typ, Index: 0}, {Kind: FieldStep, Type: typ.Field(0).Type, Index: 0}}, "/version", false},
+		{Path{{Kind: FieldStep, Type: typ, Index: 3}, {Kind: IndexStep, Type: typ.Field(3).Type, Index: 4}}, "/tags/4", false},
+		{Path{{Kind: FieldStep, Type: typ, Index: 6}}, "", true},
+		{Path{{Kind: FieldStep, Type: typ, Index: 11}}, "", true},
+		{Path{{Kind: MapKeyStep, Type: reflect.TypeOf(map[float64]int{}), Key: reflect.ValueOf(1.5)}}, "", true},
+		{Path{{Kind: MapKeyStep, Type: reflect.TypeOf(map[uint8]int{}), Key: reflect.ValueOf(uint8(7))}}, "/7", false},
+	}
+	for i, test := range tests {
+		got, err := test.path.JSONPointer()
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("[%d]: want %q (error %t), got %q (%v)", i, test.want, test.err, got, err)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package deep
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// sortedKeys returns the keys of map v in a deterministic order.
+//
+// Numbers are ordered numerically, with NaN before other values, and complex
+// numbers ordered by their real, then imaginary parts. Strings are ordered
+// lexically, and false is ordered before true. Pointers and channels are
+// ordered by address. Structs and arrays are ordered by comparing each field
+// or element in turn. Interfaces are ordered with nil first, then by the name
+// of the dynamic type, then by value. Keys that are still not ordered are
+// ordered by their Go-syntax representation, then by their values, in the same
+// manner. Entries with keys that cannot be distinguished, such as NaN, and
+// equal values are therefore ordered consistently.
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys, _ := sortedEntries(v)
+	return keys
+}
+
+// sortedEntries returns the keys of map v in the same order as sortedKeys,
+// along with the corresponding values. Unlike MapIndex, this locates the
+// values of keys that are not equal to themselves, such as NaN.
+func sortedEntries(v reflect.Value) (keys, values []reflect.Value) {
+	keys = make([]reflect.Value, 0, v.Len())
+	values = make([]reflect.Value, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		keys = append(keys, iter.Key())
+		values = append(values, iter.Value())
+	}
+	sort.Stable(entries{keys, values})
+	return keys, values
+}
+
+// entries sorts the entries of a map by key.
+type entries struct {
+	keys, values []reflect.Value
+}
+
+func (e entries) Len() int { return len(e.keys) }
+func (e entries) Less(i, j int) bool {
+	c := compareKeys(e.keys[i], e.keys[j])
+	if c == 0 {
+		c = compareOrdered(fmt.Sprintf("%#v", e.keys[i]), fmt.Sprintf("%#v", e.keys[j]))
+	}
+	if c == 0 {
+		c = compareKeys(e.values[i], e.values[j])
+	}
+	if c == 0 {
+		c = compareOrdered(fmt.Sprintf("%#v", e.values[i]), fmt.Sprintf("%#v", e.values[j]))
+	}
+	return c < 0
+}
+func (e entries) Swap(i, j int) {
+	e.keys[i], e.keys[j] = e.keys[j], e.keys[i]
+	e.values[i], e.values[j] = e.values[j], e.values[i]
+}
+
+// deepKeysEqual compares maps x and y, matching their keys by equivalence
+// rather than identity. Keys are first matched by identity, then each
+// remaining key of x is matched with the first remaining equivalent key of y,
+// preferring keys with equivalent values.
+func (s *compareState) deepKeysEqual(x, y reflect.Value, depth int) bool {
+	xkeys, xvalues := sortedEntries(x)
+	ykeys, yvalues := sortedEntries(y)
+	yindex := make(map[int]bool, len(ykeys))
+	match := make([]int, len(xkeys))
+	for i, k := range xkeys {
+		match[i] = -1
+		if !y.MapIndex(k).IsValid() {
+			continue
+		}
+		// Keys found by MapIndex are ordered the same only if they are equal.
+		j := sort.Search(len(ykeys), func(j int) bool { return compareKeys(ykeys[j], k) >= 0 })
+		for ; j < len(ykeys) && compareKeys(ykeys[j], k) == 0; j++ {
+			if !yindex[j] {
+				match[i] = j
+				yindex[j] = true
+				break
+			}
+		}
+	}
+	// Prefer keys whose values are also equivalent, so that the result does
+	// not depend on the order of keys that cannot be ordered, such as NaN.
+	for _, values := range []bool{true, false} {
+		for i, k := range xkeys {
+			if match[i] >= 0 {
+				continue
+			}
+			for j, yk := range ykeys {
+				if yindex[j] || !s.equivalent(k, yk, depth+1) {
+					continue
+				}
+				if values {
+					s.push(Step{Kind: MapKeyStep, Type: x.Type(), Key: k})
+					eq := s.equivalent(xvalues[i], yvalues[j], depth+1)
+					s.pop()
+					if !eq {
+						continue
+					}
+				}
+				match[i] = j
+				yindex[j] = true
+				break
+			}
+		}
+	}
+
+	eq := true
+	for i, k := range xkeys {
+		s.push(Step{Kind: MapKeyStep, Type: x.Type(), Key: k})
+		if j := match[i]; j >= 0 {
+			if !s.deepValueEqual(xvalues[i], yvalues[j], depth+1) {
+				eq = false
+			}
+		} else if !s.ignore(xvalues[i], reflect.Value{}) {
+			s.append(MissingKey, xvalues[i], reflect.Value{})
+			eq = false
+		}
+		s.pop()
+		if s.full() {
+			return false
+		}
+	}
+	for j, k := range ykeys {
+		if yindex[j] {
+			continue
+		}
+		s.push(Step{Kind: MapKeyStep, Type: y.Type(), Key: k})
+		if !s.ignore(reflect.Value{}, yvalues[j]) {
+			s.append(ExtraKey, reflect.Value{}, yvalues[j])
+			eq = false
+		}
+		s.pop()
+		if s.full() {
+			return false
+		}
+	}
+	return eq
+}
+
+// compareKeys returns -1, 0, or 1 depending on whether a is ordered before,
+// the same as, or after b. a and b must have the same type. Returns 0 if the
+// keys cannot be ordered.
+func compareKeys(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return compareOrdered(a.Uint(), b.Uint())
+	case reflect.String:
+		return compareOrdered(a.String(), b.String())
+	case reflect.Float32, reflect.Float64:
+		return compareFloats(a.Float(), b.Float())
+	case reflect.Complex64, reflect.Complex128:
+		ac, bc := a.Complex(), b.Complex()
+		if c := compareFloats(real(ac), real(bc)); c != 0 {
+			return c
+		}
+		return compareFloats(imag(ac), imag(bc))
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case !a.Bool():
+			return -1
+		}
+		return 1
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		return compareOrdered(a.Pointer(), b.Pointer())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if c := compareKeys(a.Field(i), b.Field(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if c := compareKeys(a.Index(i), b.Index(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Interface:
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return -1
+		case b.IsNil():
+			return 1
+		}
+		ae, be := a.Elem(), b.Elem()
+		if ae.Type() != be.Type() {
+			return compareOrdered(ae.Type().String(), be.Type().String())
+		}
+		return compareKeys(ae, be)
+	}
+	return 0
+}
+
+// compareFloats compares floats, ordering NaN before other values.
+func compareFloats(a, b float64) int {
+	switch an, bn := math.IsNaN(a), math.IsNaN(b); {
+	case an && bn:
+		return 0
+	case an:
+		return -1
+	case bn:
+		return 1
+	}
+	return compareOrdered(a, b)
+}
+
+// compareOrdered compares values of an ordered type.
+func compareOrdered[T int64 | uint64 | uintptr | float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
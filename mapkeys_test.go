@@ -0,0 +1,144 @@
+package deep
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSortedKeys(t *testing.T) {
+	type pair struct {
+		A int
+		B string
+	}
+	tests := []struct {
+		m    interface{}
+		want []interface{}
+	}{
+		{map[int]bool{3: true, -1: true, 2: true, 10: true}, []interface{}{-1, 2, 3, 10}},
+		{map[uint8]bool{3: true, 1: true, 2: true}, []interface{}{uint8(1), uint8(2), uint8(3)}},
+		{map[string]bool{"b": true, "a": true, "B": true, "": true}, []interface{}{"", "B", "a", "b"}},
+		{map[float64]bool{1.5: true, math.Inf(-1): true, 0: true}, []interface{}{math.Inf(-1), 0.0, 1.5}},
+		{map[complex128]bool{1 + 2i: true, 1 + 1i: true, 0 + 5i: true}, []interface{}{0 + 5i, 1 + 1i, 1 + 2i}},
+		{map[bool]int{true: 1, false: 0}, []interface{}{false, true}},
+		{map[pair]bool{{2, "a"}: true, {1, "b"}: true, {1, "a"}: true}, []interface{}{pair{1, "a"}, pair{1, "b"}, pair{2, "a"}}},
+		{map[[2]int]bool{{2, 1}: true, {1, 2}: true}, []interface{}{[2]int{1, 2}, [2]int{2, 1}}},
+		{map[interface{}]bool{"a": true, 2: true, 1: true, nil: true, 1.5: true}, []interface{}{nil, 1.5, 1, 2, "a"}},
+	}
+	for i, test := range tests {
+		for run := 0; run < 5; run++ {
+			keys := sortedKeys(reflect.ValueOf(test.m))
+			got := make([]interface{}, len(keys))
+			for j, k := range keys {
+				got[j] = k.Interface()
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("[%d]: want %v, got %v", i, test.want, got)
+				break
+			}
+		}
+	}
+
+	nan := map[float64]int{math.NaN(): 1, 1: 2, math.NaN(): 3}
+	keys := sortedKeys(reflect.ValueOf(nan))
+	if len(keys) != 3 || !math.IsNaN(keys[0].Float()) || !math.IsNaN(keys[1].Float()) || keys[2].Float() != 1 {
+		t.Errorf("want NaN keys first, got %v", keys)
+	}
+}
+
+func TestMapDiffOrder(t *testing.T) {
+	x := map[string]int{}
+	y := map[string]int{}
+	for _, k := range []string{"e", "b", "d", "a", "c"} {
+		x[k] = 1
+		y[k] = 2
+	}
+	c := newComparer("MaxDiffs", 3)
+	want := []string{"map[a]: 1 != 2", "map[b]: 1 != 2", "map[c]: 1 != 2"}
+	for run := 0; run < 10; run++ {
+		if got := diffStrings(c.Equal(x, y)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("want %q, got %q", want, got)
+		}
+	}
+
+	// Entries with NaN keys are ordered by value.
+	nan := map[float64]int{}
+	for _, v := range []int{3, 5, 1, 4, 2} {
+		nan[math.NaN()] = v
+	}
+	c = newComparer("MaxDiffs", 0)
+	want = []string{"map[NaN]: 1 != <no key>", "map[NaN]: 2 != <no key>", "map[NaN]: 3 != <no key>", "map[NaN]: 4 != <no key>", "map[NaN]: 5 != <no key>"}
+	for run := 0; run < 50; run++ {
+		if got := diffStrings(c.Equal(nan, map[float64]int{})); !reflect.DeepEqual(got, want) {
+			t.Fatalf("NaN: want %q, got %q", want, got)
+		}
+	}
+}
+
+func TestDeepMapKeys(t *testing.T) {
+	nan := math.NaN()
+	x := map[float64]int{nan: 1, 1: 2}
+	y := map[float64]int{nan: 1, 1: 2}
+
+	c := newComparer("MaxDiffs", 100)
+	if diffs := c.Equal(x, y); diffs == nil {
+		t.Errorf("want diffs for NaN keys matched by identity")
+	}
+	c.DeepMapKeys = true
+	if diffs := c.Equal(x, y); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+	y[nan] = 3
+	diffs := c.Equal(x, y)
+	if len(diffs) != 1 || diffs[0].Kind != ExtraKey {
+		t.Errorf("want one extra NaN key, got %v", diffs)
+	}
+
+	// Keys that cannot be ordered are matched with keys having equivalent
+	// values, so the result does not depend on the order of the map.
+	nx := map[float64]int{}
+	ny := map[float64]int{}
+	nz := map[float64]int{}
+	for _, v := range []int{1, 2, 3} {
+		nx[math.NaN()] = v
+		ny[math.NaN()] = 4 - v
+		nz[math.NaN()] = v + 1
+	}
+	for run := 0; run < 50; run++ {
+		if diffs := c.Equal(nx, ny); diffs != nil {
+			t.Fatalf("want no diffs, got %v", diffs)
+		}
+		want := []string{"map[NaN]: 1 != 4"}
+		if got := diffStrings(c.Equal(nx, nz)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("want %q, got %q", want, got)
+		}
+	}
+
+	// Pointer keys are matched by the values they point to.
+	a1, a2, b := &basic{1, 2}, &basic{1, 2}, &basic{3, 4}
+	px := map[*basic]string{a1: "a", b: "b"}
+	py := map[*basic]string{a2: "A", b: "b"}
+	want := []string{"map[&{1 2}]: a != A"}
+	if got := diffStrings(c.Equal(px, py)); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	c.DeepMapKeys = false
+	want = []string{"map[&{1 2}]: a != <no key>", "map[&{1 2}]: <no key> != A"}
+	if got := diffStrings(c.Equal(px, py)); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	// Keys are compared with the configured tolerances, preferring identical
+	// keys.
+	c = newComparer("MaxDiffs", 100, "DeepMapKeys", true, "FloatAbsTolerance", 0.5)
+	fx := map[float64]string{1: "a", 1.2: "b"}
+	fy := map[float64]string{1.2: "b", 1.1: "a"}
+	if diffs := c.Equal(fx, fy); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+	fy = map[float64]string{1.1: "a", 3: "c"}
+	want = []string{"map[1.2]: b != <no key>", "map[3]: <no key> != c"}
+	if got := diffStrings(c.Equal(fx, fy)); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
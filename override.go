@@ -0,0 +1,94 @@
+package deep
+
+import "math/big"
+
+// Override applies a different configuration to part of the compared values.
+type Override struct {
+	// Path is a path pattern, in the same form as IgnorePaths. Equal panics if
+	// the pattern is malformed.
+	Path string
+	// Configure modifies the configuration used to compare the values located
+	// at a path matching Path, and the values nested within them. It receives
+	// a copy of the configuration in effect at the matching path, including
+	// any overrides that apply to enclosing values, so overrides may be
+	// nested. Configure may register functions and keys with the Comparer,
+	// which affect only the overridden values.
+	//
+	// Changes to MaxDiffs and MaxNodes are ignored, since the limits apply to
+	// the entire comparison.
+	Configure func(c *Comparer)
+}
+
+// savedConfig is a configuration replaced by an override.
+type savedConfig struct {
+	// depth is the length of the path at which the override was applied.
+	depth          int
+	comparer       Comparer
+	floatx, floaty *big.Float
+	ignorePaths    []pattern
+	unorderedPaths []pattern
+	overridePaths  []pattern
+	plans          *configPlans
+}
+
+// configure prepares the state derived from the configuration of the
+// Comparer.
+func (s *compareState) configure() {
+	s.ignorePaths = mustParsePatterns(s.IgnorePaths)
+	s.unorderedPaths = mustParsePatterns(s.UnorderedPaths)
+	s.overridePaths = make([]pattern, len(s.Overrides))
+	for i, o := range s.Overrides {
+		s.overridePaths[i] = mustParsePatterns([]string{o.Path})[0]
+	}
+	cache := s.cache
+	if cache == nil {
+		cache = &defaultPlans
+	}
+	s.plans = cache.config(&s.Comparer)
+	s.floatx, s.floaty = nil, nil
+	if s.FloatPrecision > 0 {
+		s.floatx = new(big.Float).SetPrec(uint(s.FloatPrecision))
+		s.floaty = new(big.Float).SetPrec(uint(s.FloatPrecision))
+	}
+}
+
+// override applies o to the values located at the current path, until the
+// path is popped.
+func (s *compareState) override(o Override) {
+	s.saved = append(s.saved, savedConfig{
+		depth:          len(s.stack),
+		comparer:       s.Comparer,
+		floatx:         s.floatx,
+		floaty:         s.floaty,
+		ignorePaths:    s.ignorePaths,
+		unorderedPaths: s.unorderedPaths,
+		overridePaths:  s.overridePaths,
+		plans:          s.plans,
+	})
+	c := s.Comparer
+	// Prevent appends from modifying the slices of the previous
+	// configuration.
+	c.IgnorePaths = c.IgnorePaths[:len(c.IgnorePaths):len(c.IgnorePaths)]
+	c.IgnoreTypes = c.IgnoreTypes[:len(c.IgnoreTypes):len(c.IgnoreTypes)]
+	c.UnorderedTypes = c.UnorderedTypes[:len(c.UnorderedTypes):len(c.UnorderedTypes)]
+	c.UnorderedPaths = c.UnorderedPaths[:len(c.UnorderedPaths):len(c.UnorderedPaths)]
+	c.Overrides = c.Overrides[:len(c.Overrides):len(c.Overrides)]
+	if o.Configure != nil {
+		o.Configure(&c)
+	}
+	c.MaxDiffs = s.MaxDiffs
+	s.Comparer = c
+	s.configure()
+}
+
+// restore restores the configuration replaced by the most recent override.
+func (s *compareState) restore() {
+	saved := s.saved[len(s.saved)-1]
+	s.saved = s.saved[:len(s.saved)-1]
+	s.Comparer = saved.comparer
+	s.floatx, s.floaty = saved.floatx, saved.floaty
+	s.ignorePaths = saved.ignorePaths
+	s.unorderedPaths = saved.unorderedPaths
+	s.overridePaths = saved.overridePaths
+	s.plans = saved.plans
+}
@@ -0,0 +1,104 @@
+package deep
+
+import (
+	"reflect"
+	"testing"
+)
+
+type overrideRecord struct {
+	Name      string
+	Telemetry []float64
+	Tags      []string
+	Members   []string
+	Nested    *overrideRecord
+}
+
+func TestOverrides(t *testing.T) {
+	x := overrideRecord{
+		Name:      "a",
+		Telemetry: []float64{0.5},
+		Tags:      nil,
+		Members:   []string{"a", "b"},
+		Nested:    &overrideRecord{Telemetry: []float64{0.5}, Members: []string{"a", "b"}},
+	}
+	y := overrideRecord{
+		Name:      "a",
+		Telemetry: []float64{0.50000095367431640625},
+		Tags:      []string{},
+		Members:   []string{"b", "a"},
+		Nested:    &overrideRecord{Telemetry: []float64{0.50000095367431640625}, Members: []string{"b", "a"}},
+	}
+
+	c := newComparer("MaxDiffs", 100, "FloatPrecision", 0)
+	if diffs := c.Equal(x, y); len(diffs) < 4 {
+		t.Fatalf("want diffs without overrides, got %v", diffs)
+	}
+
+	c.Overrides = []Override{
+		{Path: ".Telemetry", Configure: func(c *Comparer) { c.FloatPrecision = 10 }},
+		{Path: ".Tags", Configure: func(c *Comparer) { c.NilSlicesAreEmpty = true }},
+		{Path: ".Members", Configure: func(c *Comparer) { c.UnorderedSlices = true }},
+	}
+	want := []string{
+		"struct.Nested.Telemetry[0]: 0.5 != 0.5000009536743164 (delta 9.5367431640625e-07)",
+		"struct.Nested.Members[0]: a != <no value>",
+		"struct.Nested.Members[1]: <no value> != a",
+	}
+	if got := diffStrings(c.Equal(x, y)); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	// Overrides may be nested, and may add overrides of their own.
+	c.Overrides = []Override{
+		{Path: ".Nested", Configure: func(c *Comparer) {
+			c.FloatPrecision = 10
+			c.Overrides = append(c.Overrides, Override{
+				Path:      ".Nested.Members",
+				Configure: func(c *Comparer) { c.UnorderedSlices = true },
+			})
+		}},
+		{Path: ".Nested.Members", Configure: func(c *Comparer) { c.MaxDiffs = 1000 }},
+	}
+	want = []string{
+		"struct.Telemetry[0]: 0.5 != 0.5000009536743164 (delta 9.5367431640625e-07)",
+		"struct.Tags: <nil slice> != []",
+		"struct.Members[0]: a != <no value>",
+		"struct.Members[1]: <no value> != a",
+	}
+	if got := diffStrings(c.Equal(x, y)); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if len(c.Overrides) != 2 {
+		t.Errorf("want overrides of the Comparer to be unaffected")
+	}
+
+	// Overrides may register functions that apply only to the subtree.
+	c.Overrides = []Override{
+		{Path: ".Nested", Configure: func(c *Comparer) {
+			c.RegisterFunc(func(a, b []float64) bool { return true })
+			c.IgnorePaths = append(c.IgnorePaths, ".Nested.Members")
+		}},
+	}
+	c.IgnorePaths = make([]string, 0, 10)
+	want = []string{
+		"struct.Telemetry[0]: 0.5 != 0.5000009536743164 (delta 9.5367431640625e-07)",
+		"struct.Tags: <nil slice> != []",
+		"struct.Members[0]: a != <no value>",
+		"struct.Members[1]: <no value> != a",
+	}
+	if got := diffStrings(c.Equal(x, y)); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	// The spare capacity of IgnorePaths must not have been written to.
+	if c.funcs != nil || len(c.IgnorePaths) != 0 || c.IgnorePaths[:1][0] != "" {
+		t.Errorf("want Comparer to be unaffected")
+	}
+
+	c.Overrides = []Override{{Path: ".A]"}}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("want panic for invalid pattern")
+		}
+	}()
+	c.Equal(x, y)
+}
@@ -0,0 +1,390 @@
+package deep
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+// OpKind indicates the kind of an Op.
+type OpKind int
+
+const (
+	// SetOp sets the value located at the path.
+	SetOp OpKind = iota
+	// InsertOp inserts an element into a slice or array at the index of the
+	// last step of the path, or adds the key of the last step to a map.
+	InsertOp
+	// DeleteOp removes the element of a slice or array at the index of the
+	// last step of the path, or removes the key of the last step from a map.
+	DeleteOp
+)
+
+var opKindStrings = [...]string{
+	SetOp:    "Set",
+	InsertOp: "Insert",
+	DeleteOp: "Delete",
+}
+
+// String returns a string representation of the kind.
+func (k OpKind) String() string {
+	if k < 0 || int(k) >= len(opKindStrings) {
+		return fmt.Sprintf("OpKind(%d)", int(k))
+	}
+	return opKindStrings[k]
+}
+
+// Op is a single operation of a Patch.
+type Op struct {
+	// Kind is the kind of operation.
+	Kind OpKind
+	// Path is the location to which the operation applies.
+	Path Path
+	// Value is the value set or inserted by the operation. It is unused by a
+	// DeleteOp. An invalid value sets or inserts the zero value.
+	Value reflect.Value
+}
+
+// String returns a string representation of the operation. Like Diff.String,
+// the result is not guaranteed to be consistent.
+func (op Op) String() string {
+	if op.Kind == DeleteOp {
+		return op.Kind.String() + " " + op.Path.String()
+	}
+	return op.Kind.String() + " " + op.Path.String() + " = " + formatValue(op.Value)
+}
+
+// Patch is a sequence of operations that transforms one value into another.
+// Operations are applied in order. The index of an element within a Path
+// refers to the position of the element at the time the operation is applied,
+// taking previous insertions and deletions into account. An element inserted
+// at a KeyedStep is instead appended to the slice, since keyed elements are
+// matched by key rather than by position.
+type Patch []Op
+
+// NewPatch returns a Patch that, when applied to the left value of the
+// comparison that produced diffs, causes it to become equivalent to the right
+// value. Values of the patch may share memory with the right value.
+//
+// The diffs must describe every difference between the values, so they should
+// be produced by a Comparer whose MaxDiffs does not truncate the result. The
+// diffs must also be in the order produced by the Comparer.
+//
+// Arrays and slices compared without regard to order, or by identity key, are
+// transformed into values that are equivalent under the same comparison,
+// rather than values with the same order.
+func NewPatch(diffs []Diff) (Patch, error) {
+	p := make(Patch, 0, len(diffs))
+	// Net number of insertions minus deletions made to each array or slice,
+	// for converting indices to the current position of an element.
+	offsets := map[string]int{}
+	for _, d := range diffs {
+		op := Op{Path: make(Path, len(d.Path)), Value: d.Right}
+		copy(op.Path, d.Path)
+		switch d.Kind {
+		case ValueMismatch, TypeMismatch, NilMismatch:
+			op.Kind = SetOp
+		case ExtraKey, ExtraElement:
+			op.Kind = InsertOp
+		case MissingKey, MissingElement:
+			op.Kind = DeleteOp
+			op.Value = reflect.Value{}
+		default:
+			return nil, fmt.Errorf("deep: NewPatch: cannot patch %s at %s", d.Kind, d.Path)
+		}
+		if op.Value.IsValid() {
+			v, ok := exportValue(op.Value)
+			if !ok {
+				return nil, fmt.Errorf("deep: NewPatch: value at %s obtained through unexported field", d.Path)
+			}
+			op.Value = v
+		}
+		for i := range op.Path {
+			step := &op.Path[i]
+			if step.Kind == MapKeyStep {
+				k, ok := exportValue(step.Key)
+				if !ok {
+					return nil, fmt.Errorf("deep: NewPatch: map key at %s obtained through unexported field", d.Path)
+				}
+				step.Key = k
+			}
+			if step.Kind != IndexStep && step.Kind != KeyedStep {
+				continue
+			}
+			key := containerKey(op.Path[:i])
+			switch {
+			case i == len(op.Path)-1 && d.Kind == MissingElement:
+				step.Index += offsets[key]
+				offsets[key]--
+			case i == len(op.Path)-1 && d.Kind == ExtraElement:
+				offsets[key]++
+			case step.Kind == KeyedStep:
+				// Located by its position within the left value.
+				step.Index += offsets[key]
+			}
+		}
+		p = append(p, op)
+	}
+	return p, nil
+}
+
+// containerKey returns a string that identifies the value located at path.
+func containerKey(path Path) string {
+	var b strings.Builder
+	for _, step := range path {
+		switch step.Kind {
+		case IndexStep, KeyedStep:
+			fmt.Fprintf(&b, "[%d]", step.Index)
+		case MapKeyStep:
+			fmt.Fprintf(&b, "[%s:%s]", step.Key.Type(), formatValue(step.Key))
+		default:
+			fmt.Fprintf(&b, "%d%s", step.Kind, step.segment())
+		}
+	}
+	return b.String()
+}
+
+// Apply applies p to the value pointed to by target. target must be a
+// non-nil pointer to a value of the same type as the left value of the
+// comparison from which the patch was produced. If the left value is itself a
+// pointer, target may instead be the left value.
+//
+// Operations are applied in order. If an operation fails, an error is
+// returned, and the target is left partially modified.
+func Apply(target interface{}, p Patch) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("deep: Apply: target must be a non-nil pointer")
+	}
+	root := v.Elem()
+	if len(p) > 0 && len(p[0].Path) > 0 && p[0].Path[0].Type == v.Type() {
+		root = addressable(v)
+	}
+	return applyOps(root, p, 0)
+}
+
+// applyOps applies ops to v, which is located at the first depth steps of the
+// path of each operation. v must be settable.
+func applyOps(v reflect.Value, ops []Op, depth int) (err error) {
+	if v.Kind() == reflect.Array && insertsOrDeletes(ops, depth) {
+		// Arrays are modified as slices, so that the elements shifted by an
+		// insertion are not lost before a following deletion.
+		a, path := v, ops[0].Path[:depth]
+		v = reflect.New(reflect.SliceOf(a.Type().Elem())).Elem()
+		v.Set(reflect.MakeSlice(v.Type(), a.Len(), a.Len()))
+		reflect.Copy(v, a)
+		defer func() {
+			if err == nil && v.Len() != a.Len() {
+				err = fmt.Errorf("deep: Apply: length of array at %s changed from %d to %d", path, a.Len(), v.Len())
+				return
+			}
+			reflect.Copy(a, v)
+		}()
+	}
+	for len(ops) > 0 {
+		op := ops[0]
+		if len(op.Path) == depth {
+			if op.Kind != SetOp {
+				return fmt.Errorf("deep: Apply: %s requires an element at %s", op.Kind, op.Path)
+			}
+			if err := setValue(v, op); err != nil {
+				return err
+			}
+			ops = ops[1:]
+			continue
+		}
+		step := op.Path[depth]
+		if len(op.Path) == depth+1 && op.Kind != SetOp {
+			if err := insertOrDelete(v, step, op); err != nil {
+				return err
+			}
+			ops = ops[1:]
+			continue
+		}
+		// Apply consecutive operations through the same step together.
+		n := 1
+		for n < len(ops) &&
+			len(ops[n].Path) > depth &&
+			sameStep(ops[n].Path[depth], step) &&
+			!(len(ops[n].Path) == depth+1 && ops[n].Kind != SetOp) {
+			n++
+		}
+		if err := applyStep(v, step, ops[:n], depth); err != nil {
+			return err
+		}
+		ops = ops[n:]
+	}
+	return nil
+}
+
+// insertsOrDeletes returns whether any of ops inserts or deletes an element of
+// the value located at the first depth steps of its path.
+func insertsOrDeletes(ops []Op, depth int) bool {
+	for _, op := range ops {
+		if len(op.Path) == depth+1 && op.Kind != SetOp {
+			return true
+		}
+	}
+	return false
+}
+
+// sameStep returns whether a and b select the same value.
+func sameStep(a, b Step) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	switch a.Kind {
+	case FieldStep, IndexStep, KeyedStep:
+		return a.Index == b.Index
+	case MapKeyStep:
+		return a.Key.Type() == b.Key.Type() && a.Key.Interface() == b.Key.Interface()
+	}
+	return true
+}
+
+// applyStep applies ops to the value selected from v by step.
+func applyStep(v reflect.Value, step Step, ops []Op, depth int) error {
+	path := ops[0].Path[:depth+1]
+	switch step.Kind {
+	case FieldStep:
+		if v.Kind() != reflect.Struct || step.Index >= v.NumField() {
+			return fmt.Errorf("deep: Apply: no field at %s", path)
+		}
+		return applyOps(settable(v.Field(step.Index)), ops, depth+1)
+	case IndexStep, KeyedStep:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array ||
+			step.Index < 0 || step.Index >= v.Len() {
+			return fmt.Errorf("deep: Apply: no element at %s", path)
+		}
+		return applyOps(v.Index(step.Index), ops, depth+1)
+	case MapKeyStep:
+		if v.Kind() != reflect.Map {
+			return fmt.Errorf("deep: Apply: no map at %s", path[:depth])
+		}
+		e := v.MapIndex(step.Key)
+		if !e.IsValid() {
+			return fmt.Errorf("deep: Apply: no key at %s", path)
+		}
+		e = addressable(e)
+		if err := applyOps(e, ops, depth+1); err != nil {
+			return err
+		}
+		v.SetMapIndex(step.Key, e)
+		return nil
+	case IndirectStep:
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return fmt.Errorf("deep: Apply: no pointer at %s", path[:depth])
+		}
+		return applyOps(v.Elem(), ops, depth+1)
+	case ElemStep:
+		if v.Kind() != reflect.Interface || v.IsNil() {
+			return fmt.Errorf("deep: Apply: no interface at %s", path[:depth])
+		}
+		for len(ops) > 0 {
+			if len(ops[0].Path) == depth+1 {
+				// Replaces the element, possibly with a value of another
+				// type.
+				if err := setValue(v, ops[0]); err != nil {
+					return err
+				}
+				ops = ops[1:]
+				continue
+			}
+			n := 1
+			for n < len(ops) && len(ops[n].Path) > depth+1 {
+				n++
+			}
+			e := addressable(v.Elem())
+			if err := applyOps(e, ops[:n], depth+1); err != nil {
+				return err
+			}
+			v.Set(e)
+			ops = ops[n:]
+		}
+		return nil
+	}
+	return fmt.Errorf("deep: Apply: unknown step %s at %s", step.Kind, path)
+}
+
+// insertOrDelete applies an InsertOp or DeleteOp to v, the container selected
+// from by step.
+func insertOrDelete(v reflect.Value, step Step, op Op) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		if step.Kind != IndexStep && step.Kind != KeyedStep {
+			break
+		}
+		i, n := step.Index, v.Len()
+		if op.Kind == InsertOp && step.Kind == KeyedStep {
+			// Elements with the same key are matched in order, and an
+			// inserted element follows those matched with the same key.
+			i = n
+		}
+		if op.Kind == InsertOp {
+			if i < 0 || i > n {
+				return fmt.Errorf("deep: Apply: index out of range at %s", op.Path)
+			}
+			s := reflect.MakeSlice(v.Type(), n+1, n+1)
+			reflect.Copy(s, v.Slice(0, i))
+			if err := setValue(s.Index(i), op); err != nil {
+				return err
+			}
+			reflect.Copy(s.Slice(i+1, n+1), v.Slice(i, n))
+			v.Set(s)
+			return nil
+		}
+		if i < 0 || i >= n {
+			return fmt.Errorf("deep: Apply: index out of range at %s", op.Path)
+		}
+		s := reflect.MakeSlice(v.Type(), n-1, n-1)
+		reflect.Copy(s, v.Slice(0, i))
+		reflect.Copy(s.Slice(i, n-1), v.Slice(i+1, n))
+		v.Set(s)
+		return nil
+	case reflect.Map:
+		if step.Kind != MapKeyStep {
+			break
+		}
+		if op.Kind == InsertOp {
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(e, op); err != nil {
+				return err
+			}
+			v.SetMapIndex(step.Key, e)
+			return nil
+		}
+		v.SetMapIndex(step.Key, reflect.Value{})
+		return nil
+	}
+	return fmt.Errorf("deep: Apply: cannot %s at %s", strings.ToLower(op.Kind.String()), op.Path)
+}
+
+// setValue sets v to the value of op.
+func setValue(v reflect.Value, op Op) error {
+	if !op.Value.IsValid() {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if !op.Value.Type().AssignableTo(v.Type()) {
+		return fmt.Errorf("deep: Apply: cannot assign %s to %s at %s", op.Value.Type(), v.Type(), op.Path)
+	}
+	if !op.Value.CanInterface() {
+		return fmt.Errorf("deep: Apply: value at %s obtained through unexported field", op.Path)
+	}
+	v.Set(op.Value)
+	return nil
+}
+
+// settable returns a settable version of the addressable value v, even if v
+// was obtained through unexported fields.
+func settable(v reflect.Value) reflect.Value {
+	if v.CanSet() {
+		return v
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
@@ -0,0 +1,148 @@
+package deep
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+type patchRecord struct {
+	Name  string
+	Tags  []string
+	Attrs map[string]int
+	Ptr   *basic
+	Any   interface{}
+	Grid  [3]int
+	inner int
+}
+
+// roundTrip patches a copy of x with the differences between x and y, and
+// reports whether the result is equivalent to y.
+func roundTrip(t *testing.T, c Comparer, x, y interface{}) {
+	t.Helper()
+	p, err := NewPatch(c.Equal(x, y))
+	if err != nil {
+		t.Errorf("NewPatch(%v, %v): %s", x, y, err)
+		return
+	}
+	target := reflect.New(reflect.TypeOf(x))
+	target.Elem().Set(reflect.ValueOf(x))
+	if err := Apply(target.Interface(), p); err != nil {
+		t.Errorf("Apply(%v, %v): %s", x, p, err)
+		return
+	}
+	if diffs := c.Equal(target.Elem().Interface(), y); diffs != nil {
+		t.Errorf("patch of %v to %v: want no diffs, got %v", x, y, diffs)
+	}
+}
+
+func TestPatchRoundTrip(t *testing.T) {
+	c := newComparer("MaxDiffs", 1000, "CompareUnexportedFields", true)
+	tests := []struct{ x, y interface{} }{
+		{1, 2},
+		{basic{1, 2}, basic{3, 4}},
+		{[]int{1, 2, 3, 4, 5}, []int{0, 2, 3, 5, 6, 7}},
+		{[]int{1, 2, 3}, []int{}},
+		{[]int(nil), []int{1, 2}},
+		{[]basic{{1, 1}, {2, 2}}, []basic{{2, 3}, {4, 4}}},
+		{[][]int{{1, 2}, {3}}, [][]int{{1}, {4, 3}, {5}}},
+		{[3]int{1, 2, 3}, [3]int{2, 3, 4}},
+		{map[string]int{"a": 1, "b": 2}, map[string]int{"b": 3, "c": 4}},
+		{map[int][]int{1: {1, 2}}, map[int][]int{1: {2, 3}}},
+		{&basic{1, 2}, &basic{1, 3}},
+		{
+			patchRecord{Name: "a", Tags: []string{"x", "y"}, Attrs: map[string]int{"k": 1}, Ptr: &basic{1, 1}, Any: []int{1}, inner: 1},
+			patchRecord{Name: "b", Tags: []string{"y", "z"}, Attrs: nil, Ptr: nil, Any: []int{1, 2}, Grid: [3]int{1, 2, 3}, inner: 2},
+		},
+		{
+			patchRecord{Any: 1, Attrs: map[string]int{}},
+			patchRecord{Any: "a", Ptr: &basic{}},
+		},
+	}
+	for _, test := range tests {
+		roundTrip(t, c, test.x, test.y)
+	}
+
+	c.UnorderedSlices = true
+	roundTrip(t, c, []int{1, 2, 2, 3}, []int{4, 2, 1, 1})
+
+	c = newComparer("MaxDiffs", 1000)
+	c.RegisterKeyField(reflect.TypeOf(keyedRecord{}), ".ID")
+	roundTrip(t, c,
+		[]keyedRecord{{ID: 1, Name: "a"}, {ID: 42, Name: "b"}, {ID: 3, Name: "c"}, {ID: 5, Name: "e"}},
+		[]keyedRecord{{ID: 42, Name: "B"}, {ID: 4, Name: "d"}, {ID: 1, Name: "a"}, {ID: 5, Name: "E"}},
+	)
+
+	// Elements with repeated keys keep their order.
+	roundTrip(t, c,
+		[]keyedRecord{{ID: 2, Name: "x"}, {ID: 3, Name: "y"}, {ID: 1, Name: "a"}},
+		[]keyedRecord{{ID: 1, Name: "a"}, {ID: 1, Name: "b"}, {ID: 3, Name: "y"}},
+	)
+	r := rand.New(rand.NewSource(1))
+	records := func() []keyedRecord {
+		v := make([]keyedRecord, r.Intn(6))
+		for i := range v {
+			v[i] = keyedRecord{ID: r.Intn(3), Name: string(rune('a' + r.Intn(3)))}
+		}
+		return v
+	}
+	for n := 0; n < 1000; n++ {
+		roundTrip(t, c, records(), records())
+	}
+}
+
+func TestNewPatch(t *testing.T) {
+	c := newComparer("MaxDiffs", 100)
+	p, err := NewPatch(c.Equal([]int{1, 2, 3, 4}, []int{2, 4, 5}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Delete slice[0]",
+		"Delete slice[1]",
+		"Insert slice[2] = 5",
+	}
+	got := make([]string, len(p))
+	for i, op := range p {
+		got[i] = op.String()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	_, err = NewPatch(c.Equal(unexported{1, 2}, unexported{1, 3}))
+	if err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	c := newComparer("MaxDiffs", 100)
+	p, err := NewPatch(c.Equal(basic{1, 2}, basic{2, 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(basic{}, p); err == nil {
+		t.Errorf("want error for non-pointer target")
+	}
+	if err := Apply((*basic)(nil), p); err == nil {
+		t.Errorf("want error for nil target")
+	}
+	if err := Apply(&[]int{}, p); err == nil {
+		t.Errorf("want error for mismatched target")
+	}
+
+	p, err = NewPatch(c.Equal([2]int{1, 2}, []int{1, 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := [2]int{1, 2}
+	if err := Apply(&a, p); err == nil {
+		t.Errorf("want error for unassignable value")
+	}
+
+	p = Patch{{Kind: InsertOp, Path: Path{{Kind: IndexStep, Index: 0}}, Value: reflect.ValueOf(0)}}
+	if err := Apply(&a, p); err == nil {
+		t.Errorf("want error for changed array length")
+	}
+}
@@ -0,0 +1,105 @@
+package deep
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// StepKind indicates the kind of a Step.
+type StepKind int
+
+const (
+	// FieldStep selects a field of a struct.
+	FieldStep StepKind = iota
+	// IndexStep selects an element of an array or slice.
+	IndexStep
+	// MapKeyStep selects the value of a map by its key.
+	MapKeyStep
+	// IndirectStep dereferences a pointer.
+	IndirectStep
+	// ElemStep unwraps the value of an interface.
+	ElemStep
+	// KeyedStep selects an element of a slice by its identity key, as
+	// registered with Comparer.RegisterKey or Comparer.RegisterKeyField.
+	KeyedStep
+)
+
+var stepKindStrings = [...]string{
+	FieldStep:    "Field",
+	IndexStep:    "Index",
+	MapKeyStep:   "MapKey",
+	IndirectStep: "Indirect",
+	ElemStep:     "Elem",
+	KeyedStep:    "Keyed",
+}
+
+// String returns a string representation of the kind.
+func (k StepKind) String() string {
+	if k < 0 || int(k) >= len(stepKindStrings) {
+		return fmt.Sprintf("StepKind(%d)", int(k))
+	}
+	return stepKindStrings[k]
+}
+
+// Step is a single step from a value to a value nested within it.
+type Step struct {
+	// Kind is the kind of step.
+	Kind StepKind
+	// Type is the type of the value to which the step is applied.
+	Type reflect.Type
+	// Name is the name of the field selected by a FieldStep, or the name of
+	// the identity key of a KeyedStep.
+	Name string
+	// Index is the index of the field selected by a FieldStep, or the index
+	// of the element selected by an IndexStep or KeyedStep. The index of an
+	// element is its position within the right value, except for a
+	// MissingElement, or a KeyedStep selecting an element present in both
+	// values, where it is the position within the left value.
+	Index int
+	// Key is the key selected by a MapKeyStep, or the identity key of the
+	// element selected by a KeyedStep. The key of a map obtained through
+	// unexported fields cannot be used with Interface, in which case
+	// CanInterface returns false.
+	Key reflect.Value
+}
+
+// segment returns the string representation of the step, as it appears
+// within a Path. Steps that do not change the representation of a value
+// return an empty string.
+func (s Step) segment() string {
+	switch s.Kind {
+	case FieldStep:
+		return "." + s.Name
+	case IndexStep:
+		return fmt.Sprintf("[%d]", s.Index)
+	case MapKeyStep:
+		return fmt.Sprintf("[%v]", s.Key)
+	case KeyedStep:
+		return "[" + s.Name + "=" + formatValue(s.Key) + "]"
+	}
+	return ""
+}
+
+// Path is a sequence of steps leading from a root value to a value nested
+// within it.
+type Path []Step
+
+// String returns a string representation of the path. The path is prefixed
+// with the kind of the outermost value that is indexed. IndirectSteps and
+// ElemSteps are not included. Like Diff.String, the result is not guaranteed
+// to be consistent.
+func (p Path) String() string {
+	var b strings.Builder
+	for _, step := range p {
+		seg := step.segment()
+		if seg == "" {
+			continue
+		}
+		if b.Len() == 0 && step.Type != nil {
+			b.WriteString(step.Type.Kind().String())
+		}
+		b.WriteString(seg)
+	}
+	return b.String()
+}
@@ -0,0 +1,145 @@
+package deep
+
+import (
+	"reflect"
+	"testing"
+)
+
+type pathOuter struct {
+	Inner *pathInner
+	List  []interface{}
+	Map   map[string]int
+}
+
+type pathInner struct {
+	Value int
+}
+
+type diffTest struct {
+	x, y   interface{}
+	kind   DiffKind
+	steps  []StepKind
+	path   string
+	string string
+}
+
+var diffTests = []diffTest{
+	{nil, 1, NilMismatch, nil, "", "<nil> != 1"},
+	{1, "1", TypeMismatch, nil, "", "int != string"},
+	{1, 2, ValueMismatch, nil, "", "1 != 2"},
+	{
+		pathOuter{Inner: &pathInner{Value: 1}},
+		pathOuter{Inner: &pathInner{Value: 2}},
+		ValueMismatch,
+		[]StepKind{FieldStep, IndirectStep, FieldStep},
+		"struct.Inner.Value",
+		"struct.Inner.Value: 1 != 2",
+	},
+	{
+		pathOuter{Inner: &pathInner{}},
+		pathOuter{},
+		NilMismatch,
+		[]StepKind{FieldStep},
+		"struct.Inner",
+		"struct.Inner: &{0} != <nil>",
+	},
+	{
+		pathOuter{List: []interface{}{1, 2}},
+		pathOuter{List: []interface{}{1, "2"}},
+		TypeMismatch,
+		[]StepKind{FieldStep, IndexStep, ElemStep},
+		"struct.List[1]",
+		"struct.List[1]: int != string",
+	},
+	{
+		pathOuter{List: []interface{}{1, 2}},
+		pathOuter{List: []interface{}{1}},
+		MissingElement,
+		[]StepKind{FieldStep, IndexStep},
+		"struct.List[1]",
+		"struct.List[1]: 2 != <no value>",
+	},
+	{
+		pathOuter{List: []interface{}{1}},
+		pathOuter{List: []interface{}{1, nil}},
+		ExtraElement,
+		[]StepKind{FieldStep, IndexStep},
+		"struct.List[1]",
+		"struct.List[1]: <no value> != <nil interface {}>",
+	},
+	{
+		pathOuter{Map: map[string]int{"a": 1}},
+		pathOuter{Map: map[string]int{}},
+		MissingKey,
+		[]StepKind{FieldStep, MapKeyStep},
+		"struct.Map[a]",
+		"struct.Map[a]: 1 != <no key>",
+	},
+	{
+		pathOuter{Map: map[string]int{}},
+		pathOuter{Map: map[string]int{"a": 1}},
+		ExtraKey,
+		[]StepKind{FieldStep, MapKeyStep},
+		"struct.Map[a]",
+		"struct.Map[a]: <no key> != 1",
+	},
+	{
+		[]int{1},
+		[]int(nil),
+		NilMismatch,
+		nil,
+		"",
+		"[1] != <nil slice>",
+	},
+}
+
+func TestDiff(t *testing.T) {
+	c := newComparer()
+	for i, test := range diffTests {
+		diffs := c.Equal(test.x, test.y)
+		if len(diffs) != 1 {
+			t.Errorf("[%d]: want 1 diff, got %d: %v", i, len(diffs), diffs)
+			continue
+		}
+		d := diffs[0]
+		if d.Kind != test.kind {
+			t.Errorf("[%d]: want kind %s, got %s", i, test.kind, d.Kind)
+		}
+		steps := make([]StepKind, len(d.Path))
+		for j, step := range d.Path {
+			steps[j] = step.Kind
+		}
+		if len(steps) == 0 {
+			steps = nil
+		}
+		if !reflect.DeepEqual(steps, test.steps) {
+			t.Errorf("[%d]: want steps %v, got %v", i, test.steps, steps)
+		}
+		if s := d.Path.String(); s != test.path {
+			t.Errorf("[%d]: want path %q, got %q", i, test.path, s)
+		}
+		if s := d.String(); s != test.string {
+			t.Errorf("[%d]: want string %q, got %q", i, test.string, s)
+		}
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	x := pathOuter{Inner: &pathInner{Value: 1}}
+	y := pathOuter{Inner: &pathInner{Value: 2}}
+	diffs := newComparer().Equal(x, y)
+	if len(diffs) != 1 {
+		t.Fatalf("want 1 diff, got %d: %v", len(diffs), diffs)
+	}
+	d := diffs[0]
+	if v := d.Left.Interface(); v != 1 {
+		t.Errorf("want left 1, got %v", v)
+	}
+	if v := d.Right.Interface(); v != 2 {
+		t.Errorf("want right 2, got %v", v)
+	}
+	step := d.Path[len(d.Path)-1]
+	if step.Type != reflect.TypeOf(pathInner{}) || step.Name != "Value" || step.Index != 0 {
+		t.Errorf("unexpected last step %+v", step)
+	}
+}
@@ -0,0 +1,124 @@
+package deep
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A pattern matches against a Path. A pattern is written in the same form as
+// the string representation of a Path, and is made up of the following
+// segments:
+//
+//	.Name   Matches the struct field Name.
+//	.*      Matches any struct field.
+//	[N]     Matches the array or slice element at index N.
+//	[key]   Matches the map value whose key is formatted as key.
+//	[N=key] Matches the slice element whose identity key N is formatted as key.
+//	[*]     Matches any element of an array, slice, or map.
+//
+// A pattern may begin with the kind of the outermost value, as produced by
+// Path.String, which is ignored. Map keys may not contain the "]" character.
+// IndirectSteps and ElemSteps are not matched by any segment; pointers and
+// interfaces are passed through transparently.
+//
+// A pattern matches a path only if every segment matches, so ".Users[*]"
+// matches each element of Users, but not Users itself, nor the fields of
+// each element.
+type pattern []patternSegment
+
+type patternSegment struct {
+	// field is true if the segment selects a struct field, and false if it
+	// selects an element.
+	field bool
+	// any is true if the segment matches any field or element.
+	any bool
+	// text is the name of the field, or the formatted index or key of the
+	// element.
+	text string
+}
+
+// parsePattern parses s into a pattern.
+func parsePattern(s string) (p pattern, err error) {
+	// Skip kind prefix.
+	if i := strings.IndexAny(s, ".["); i > 0 {
+		s = s[i:]
+	} else if i < 0 && s != "" {
+		return nil, errors.New("expected '.' or '['")
+	}
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			i := strings.IndexAny(s[1:], ".[]") + 1
+			if i == 0 {
+				i = len(s)
+			}
+			name := s[1:i]
+			if name == "" {
+				return nil, errors.New("empty field name")
+			}
+			p = append(p, patternSegment{field: true, any: name == "*", text: name})
+			s = s[i:]
+		case '[':
+			i := strings.IndexByte(s, ']')
+			if i < 0 {
+				return nil, errors.New("unclosed '['")
+			}
+			text := s[1:i]
+			p = append(p, patternSegment{any: text == "*", text: text})
+			s = s[i+1:]
+		default:
+			return nil, fmt.Errorf("unexpected character %q", s[0])
+		}
+	}
+	return p, nil
+}
+
+// mustParsePatterns parses each string in s into a pattern, panicking if a
+// pattern is invalid.
+func mustParsePatterns(s []string) []pattern {
+	if len(s) == 0 {
+		return nil
+	}
+	patterns := make([]pattern, len(s))
+	for i, s := range s {
+		p, err := parsePattern(s)
+		if err != nil {
+			panic(fmt.Sprintf("deep: invalid path pattern %q: %s", s, err))
+		}
+		patterns[i] = p
+	}
+	return patterns
+}
+
+// match returns whether the pattern matches path.
+func (p pattern) match(path Path) bool {
+	i := 0
+	for _, step := range path {
+		switch step.Kind {
+		case IndirectStep, ElemStep:
+			continue
+		}
+		if i >= len(p) || !p[i].match(step) {
+			return false
+		}
+		i++
+	}
+	return i == len(p)
+}
+
+// match returns whether the segment matches step.
+func (g patternSegment) match(step Step) bool {
+	switch step.Kind {
+	case FieldStep:
+		return g.field && (g.any || g.text == step.Name)
+	case IndexStep:
+		return !g.field && (g.any || g.text == strconv.Itoa(step.Index))
+	case MapKeyStep:
+		return !g.field && (g.any || g.text == fmt.Sprint(step.Key))
+	case KeyedStep:
+		return !g.field && (g.any || g.text == step.Name+"="+formatValue(step.Key))
+	}
+	return false
+}
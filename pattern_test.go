@@ -0,0 +1,96 @@
+package deep
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePattern(t *testing.T) {
+	valid := map[string]pattern{
+		"":             nil,
+		".A":           {{field: true, text: "A"}},
+		"struct.A":     {{field: true, text: "A"}},
+		".*":           {{field: true, any: true, text: "*"}},
+		"[1]":          {{text: "1"}},
+		"[*]":          {{any: true, text: "*"}},
+		"map[a.b][*]":  {{text: "a.b"}, {any: true, text: "*"}},
+		".A[0].B[key]": {{field: true, text: "A"}, {text: "0"}, {field: true, text: "B"}, {text: "key"}},
+	}
+	for s, want := range valid {
+		p, err := parsePattern(s)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(p, want) {
+			t.Errorf("%q: want %v, got %v", s, want, p)
+		}
+	}
+	for _, s := range []string{"A", ".", ".A.", "[1", ".A]"} {
+		if _, err := parsePattern(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+type ignoreUser struct {
+	Name      string
+	ID        int `deep:"-"`
+	CreatedAt time.Time
+	Meta      map[string]interface{}
+}
+
+type ignoreRoot struct {
+	Users []*ignoreUser
+	Cache map[string]int
+	Stamp time.Time
+}
+
+func TestIgnore(t *testing.T) {
+	x := ignoreRoot{
+		Users: []*ignoreUser{
+			{Name: "a", ID: 1, CreatedAt: time.Unix(1, 0), Meta: map[string]interface{}{"x": 1}},
+			{Name: "b", ID: 2, CreatedAt: time.Unix(2, 0)},
+		},
+		Cache: map[string]int{"a": 1},
+		Stamp: time.Unix(1, 0),
+	}
+	y := ignoreRoot{
+		Users: []*ignoreUser{
+			{Name: "a", ID: 3, CreatedAt: time.Unix(3, 0), Meta: map[string]interface{}{"x": 2}},
+			{Name: "b", ID: 4, CreatedAt: time.Unix(4, 0)},
+		},
+		Cache: map[string]int{"b": 2},
+		Stamp: time.Unix(2, 0),
+	}
+
+	c := newComparer("CompareUnexportedFields", true, "MaxDiffs", 100)
+	if diffs := c.Equal(x, y); len(diffs) != 6 {
+		t.Errorf("want 6 diffs, got %d: %v", len(diffs), diffs)
+	}
+
+	c.IgnorePaths = []string{".Users[*].CreatedAt", ".Users[0].Meta[x]", "struct.Cache"}
+	c.IgnoreTypes = []reflect.Type{reflect.TypeOf(time.Time{})}
+	if diffs := c.Equal(x, y); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+
+	c.IgnoreTypes = nil
+	if diffs := c.Equal(x, y); len(diffs) != 1 || diffs[0].Path[0].Name != "Stamp" {
+		t.Errorf("want diff at struct.Stamp, got %v", diffs)
+	}
+
+	c.IgnorePaths = []string{".Cache[a]", ".Cache[b]", ".Stamp", ".Users[*].*"}
+	if diffs := c.Equal(x, y); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+
+	c.IgnorePaths = []string{"["}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic from malformed pattern")
+		}
+	}()
+	c.Equal(x, y)
+}
@@ -0,0 +1,180 @@
+package deep
+
+import (
+	"reflect"
+	"sync"
+)
+
+// typePlan contains the information about a type that is used to compare
+// values of the type. A plan is compiled once for each type and configuration
+// of registered functions and ignored types.
+type typePlan struct {
+	// fields contains the fields of a struct type, excluding fields tagged
+	// with `deep:"-"`.
+	fields []fieldPlan
+	// cyclic is whether values of the type may be part of a cycle, requiring
+	// visits to be tracked.
+	cyclic bool
+	// scalar is whether values of the type are booleans, integers, or
+	// strings.
+	scalar bool
+	// equal is the Equal method of the type, and equalPtr is whether the
+	// method has a pointer receiver. hasEqual is false if the type has no
+	// such method.
+	equal    reflect.Method
+	equalPtr bool
+	hasEqual bool
+	// fn is the function registered for the type. hasFunc is false if no
+	// function is registered.
+	fn      reflect.Value
+	hasFunc bool
+	// ignored is whether values of the type are ignored by IgnoreTypes.
+	ignored bool
+}
+
+// fieldPlan describes a field of a struct type.
+type fieldPlan struct {
+	index    int
+	name     string
+	exported bool
+	// ignored is whether the type of the field is ignored by IgnoreTypes.
+	ignored bool
+}
+
+// maxPlanConfigs is the number of configurations for which a planCache holds
+// plans. The plans of the least recently used configuration are discarded
+// when another is needed.
+const maxPlanConfigs = 8
+
+// planCache caches the plans of types for the configurations of a Comparer. It
+// is safe for concurrent use.
+type planCache struct {
+	mu sync.Mutex
+	// configs is ordered from least to most recently used.
+	configs []*configPlans
+}
+
+// configPlans holds the plans of types for one configuration of registered
+// functions and ignored types.
+type configPlans struct {
+	funcs       map[reflect.Type]reflect.Value
+	ignoreTypes []reflect.Type
+	plans       sync.Map // map[reflect.Type]*typePlan
+}
+
+// config returns the plans for the configuration of c.
+func (pc *planCache) config(c *Comparer) *configPlans {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for i, p := range pc.configs {
+		if p.matches(c) {
+			copy(pc.configs[i:], pc.configs[i+1:])
+			pc.configs[len(pc.configs)-1] = p
+			return p
+		}
+	}
+	p := &configPlans{
+		funcs:       c.funcs,
+		ignoreTypes: append([]reflect.Type(nil), c.IgnoreTypes...),
+	}
+	if len(pc.configs) == maxPlanConfigs {
+		copy(pc.configs, pc.configs[1:])
+		pc.configs = pc.configs[:len(pc.configs)-1]
+	}
+	pc.configs = append(pc.configs, p)
+	return p
+}
+
+// matches returns whether the plans were compiled for the configuration of c.
+func (p *configPlans) matches(c *Comparer) bool {
+	if len(p.funcs) != len(c.funcs) || len(p.ignoreTypes) != len(c.IgnoreTypes) {
+		return false
+	}
+	for t, fn := range c.funcs {
+		if f, ok := p.funcs[t]; !ok || f != fn {
+			return false
+		}
+	}
+	for i, t := range c.IgnoreTypes {
+		if p.ignoreTypes[i] != t {
+			return false
+		}
+	}
+	return true
+}
+
+// plan returns the plan of t, compiling it if it is not yet cached.
+func (p *configPlans) plan(t reflect.Type) *typePlan {
+	if tp, ok := p.plans.Load(t); ok {
+		return tp.(*typePlan)
+	}
+	tp := compilePlan(t)
+	tp.fn, tp.hasFunc = p.funcs[t]
+	tp.ignored = p.ignoresType(t)
+	for i := range tp.fields {
+		tp.fields[i].ignored = p.ignoresType(t.Field(tp.fields[i].index).Type)
+	}
+	v, _ := p.plans.LoadOrStore(t, tp)
+	return v.(*typePlan)
+}
+
+// ignoresType returns whether t is one of the ignored types.
+func (p *configPlans) ignoresType(t reflect.Type) bool {
+	for _, it := range p.ignoreTypes {
+		if it == t {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePlan compiles the plan of t.
+func compilePlan(t reflect.Type) *typePlan {
+	p := &typePlan{}
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Tag.Get("deep") == "-" {
+				continue
+			}
+			p.fields = append(p.fields, fieldPlan{
+				index:    i,
+				name:     f.Name,
+				exported: f.PkgPath == "",
+			})
+		}
+	case reflect.Map, reflect.Ptr, reflect.Interface:
+		p.cyclic = true
+	case reflect.Slice:
+		// Slices of booleans, numbers, and strings cannot contain cycles.
+		switch k := t.Elem().Kind(); k {
+		case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		default:
+			p.cyclic = !isScalar(k)
+		}
+	}
+	p.scalar = isScalar(t.Kind())
+	p.equal, p.equalPtr, p.hasEqual = equalMethod(t)
+	return p
+}
+
+// isScalar returns whether k is the kind of a boolean, integer, or string.
+func isScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.String:
+		return true
+	}
+	return false
+}
+
+// defaultPlans caches plans for Comparers not created by NewComparer.
+var defaultPlans planCache
+
+// plan returns the plan of t under the current configuration.
+func (s *compareState) plan(t reflect.Type) *typePlan {
+	return s.plans.plan(t)
+}
@@ -0,0 +1,139 @@
+package deep
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestCompilePlan(t *testing.T) {
+	type T struct {
+		A int
+		b string
+		C []int `deep:"-"`
+		D map[string]int
+	}
+	p := compilePlan(reflect.TypeOf(T{}))
+	want := []fieldPlan{
+		{index: 0, name: "A", exported: true},
+		{index: 1, name: "b", exported: false},
+		{index: 3, name: "D", exported: true},
+	}
+	if !reflect.DeepEqual(p.fields, want) {
+		t.Errorf("fields: want %+v, got %+v", want, p.fields)
+	}
+	if p.cyclic || p.scalar || p.hasEqual {
+		t.Errorf("struct: want non-cyclic non-scalar plan without Equal, got %+v", p)
+	}
+
+	tests := []struct {
+		v                        interface{}
+		cyclic, scalar, hasEqual bool
+	}{
+		{0, false, true, false},
+		{"", false, true, false},
+		{1.5, false, false, false},
+		{[]int{}, false, false, false},
+		{[]float64{}, false, false, false},
+		{[][]int{}, true, false, false},
+		{map[int]int{}, true, false, false},
+		{new(int), true, false, false},
+		{parity(0), false, true, true},
+		{ptrEqual{}, false, false, true},
+	}
+	for i, test := range tests {
+		p := compilePlan(reflect.TypeOf(test.v))
+		if p.cyclic != test.cyclic || p.scalar != test.scalar || p.hasEqual != test.hasEqual {
+			t.Errorf("[%d] %T: want (%t, %t, %t), got (%t, %t, %t)", i, test.v,
+				test.cyclic, test.scalar, test.hasEqual,
+				p.cyclic, p.scalar, p.hasEqual)
+		}
+	}
+}
+
+func TestPlanCache(t *testing.T) {
+	type T struct {
+		A int
+		b int
+	}
+	x, y := T{1, 2}, T{1, 3}
+	nc := NewComparer()
+	plans := nc.cache.config(nc)
+	if p := plans.plan(reflect.TypeOf(x)); p != plans.plan(reflect.TypeOf(x)) {
+		t.Errorf("want cached plan")
+	}
+	if nc.cache.config(nc) != plans {
+		t.Errorf("want plans reused for the same configuration")
+	}
+
+	// Plans record registered functions and ignored types.
+	nc.RegisterFunc(func(a, b int) bool { return true })
+	nc.IgnoreTypes = []reflect.Type{reflect.TypeOf("")}
+	p := nc.cache.config(nc).plan(reflect.TypeOf(struct {
+		N int
+		S string
+	}{}))
+	if !p.fields[1].ignored || p.fields[0].ignored {
+		t.Errorf("want only the string field ignored, got %+v", p.fields)
+	}
+	if p := nc.cache.config(nc).plan(reflect.TypeOf(0)); !p.hasFunc || p.ignored {
+		t.Errorf("want int with function, got %+v", p)
+	}
+	if p := plans.plan(reflect.TypeOf(0)); p.hasFunc {
+		t.Errorf("want previous configuration without function")
+	}
+
+	// A copy with a different configuration uses its own plans.
+	d := *nc
+	d.IgnoreTypes = nil
+	if diffs := d.Equal("a", "b"); len(diffs) != 1 {
+		t.Errorf("want 1 diff without ignored types, got %v", diffs)
+	}
+	if diffs := nc.Equal("a", "b"); diffs != nil {
+		t.Errorf("want no diffs with ignored types, got %v", diffs)
+	}
+	d.IgnoreTypes = []reflect.Type{reflect.TypeOf(0)}
+	if diffs := d.Equal("a", "b"); len(diffs) != 1 {
+		t.Errorf("want 1 diff with other ignored types, got %v", diffs)
+	}
+
+	// Only recent configurations are kept.
+	for i := 0; i < 2*maxPlanConfigs; i++ {
+		d.IgnoreTypes = []reflect.Type{reflect.ArrayOf(i, reflect.TypeOf(0))}
+		d.Equal(x, y)
+	}
+	if n := len(nc.cache.configs); n != maxPlanConfigs {
+		t.Errorf("want %d configurations, got %d", maxPlanConfigs, n)
+	}
+
+	// Plans do not depend on other configuration.
+	c := newComparer()
+	if diffs := c.Equal(x, y); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+	c.CompareUnexportedFields = true
+	if diffs := c.Equal(x, y); len(diffs) != 1 {
+		t.Errorf("want 1 diff with CompareUnexportedFields, got %v", diffs)
+	}
+
+	// Plans are compiled safely by concurrent comparisons.
+	c = newComparer("UseEqualMethods", true)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if diffs := c.Equal([]parity{1, 2}, []parity{3, 4}); diffs != nil {
+					t.Errorf("want no diffs, got %v", diffs)
+					return
+				}
+				if diffs := c.Equal(x, y); diffs != nil {
+					t.Errorf("want no diffs, got %v", diffs)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
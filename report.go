@@ -0,0 +1,250 @@
+package deep
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Report is the result of a comparison, in a form suitable for consumption by
+// other programs. A Report can be encoded with the encoding/json package.
+type Report struct {
+	// Settings is the configuration used to make the comparison.
+	Settings Comparer
+	// LeftType and RightType are the types of the compared values. A type is
+	// nil if the corresponding value is nil.
+	LeftType, RightType reflect.Type
+	// Diffs contains the differences between the values.
+	Diffs []Diff
+}
+
+// Report compares x and y, returning a Report of the differences between
+// them.
+func (c Comparer) Report(x, y interface{}) Report {
+	return Report{
+		Settings:  c,
+		LeftType:  reflect.TypeOf(x),
+		RightType: reflect.TypeOf(y),
+		Diffs:     c.Equal(x, y),
+	}
+}
+
+// Truncated returns whether further differences may have been omitted from
+// the report due to the MaxDiffs setting.
+func (r Report) Truncated() bool {
+	return r.Settings.MaxDiffs > 0 && len(r.Diffs) >= r.Settings.MaxDiffs
+}
+
+// jsonReport is the JSON encoding of a Report.
+type jsonReport struct {
+	Equal     bool         `json:"equal"`
+	Truncated bool         `json:"truncated"`
+	LeftType  *string      `json:"left_type"`
+	RightType *string      `json:"right_type"`
+	Settings  jsonSettings `json:"settings"`
+	Diffs     []Diff       `json:"diffs"`
+}
+
+// jsonSettings is the JSON encoding of the configuration of a Comparer.
+type jsonSettings struct {
+	CompareUnexportedFields bool              `json:"compare_unexported_fields"`
+	FloatPrecision          int               `json:"float_precision"`
+	FloatAbsTolerance       float64           `json:"float_abs_tolerance"`
+	FloatRelTolerance       float64           `json:"float_rel_tolerance"`
+	FloatULPs               int               `json:"float_ulps"`
+	MaxDepth                int               `json:"max_depth"`
+	ReportMaxDepth          bool              `json:"report_max_depth"`
+	MaxDiffs                int               `json:"max_diffs"`
+	MaxNodes                int               `json:"max_nodes"`
+	NilMapsAreEmpty         bool              `json:"nil_maps_are_empty"`
+	NilSlicesAreEmpty       bool              `json:"nil_slices_are_empty"`
+	UseEqualMethods         bool              `json:"use_equal_methods"`
+	IgnorePaths             []string          `json:"ignore_paths"`
+	IgnoreTypes             []string          `json:"ignore_types"`
+	UnorderedSlices         bool              `json:"unordered_slices"`
+	UnorderedTypes          []string          `json:"unordered_types"`
+	UnorderedPaths          []string          `json:"unordered_paths"`
+	DeepMapKeys             bool              `json:"deep_map_keys"`
+	CompareSlicesByIndex    bool              `json:"compare_slices_by_index"`
+	TextDiffLength          int               `json:"text_diff_length"`
+	TextDiffContext         int               `json:"text_diff_context"`
+	Overrides               []string          `json:"overrides"`
+	Color                   string            `json:"color"`
+	Funcs                   []string          `json:"funcs"`
+	Keys                    map[string]string `json:"keys"`
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// The report is encoded as an object with the following members:
+//
+//   - "equal": whether the values are equivalent.
+//   - "truncated": the result of Truncated.
+//   - "left_type", "right_type": the types of the values, or null.
+//   - "settings": an object containing each setting of the Comparer, with
+//     types rendered as strings, and "overrides" listing the path of each
+//     Override. Additionally, "funcs" lists the types of functions
+//     registered with RegisterFunc, and "keys" maps element types to the
+//     name of the key registered with RegisterKey or RegisterKeyField.
+//   - "diffs": an array of differences, each encoded by Diff.MarshalJSON.
+func (r Report) MarshalJSON() ([]byte, error) {
+	c := r.Settings
+	rep := jsonReport{
+		Equal:     len(r.Diffs) == 0,
+		Truncated: r.Truncated(),
+		LeftType:  typeName(r.LeftType),
+		RightType: typeName(r.RightType),
+		Settings: jsonSettings{
+			CompareUnexportedFields: c.CompareUnexportedFields,
+			FloatPrecision:          c.FloatPrecision,
+			FloatAbsTolerance:       c.FloatAbsTolerance,
+			FloatRelTolerance:       c.FloatRelTolerance,
+			FloatULPs:               c.FloatULPs,
+			MaxDepth:                c.MaxDepth,
+			ReportMaxDepth:          c.ReportMaxDepth,
+			MaxDiffs:                c.MaxDiffs,
+			MaxNodes:                c.MaxNodes,
+			NilMapsAreEmpty:         c.NilMapsAreEmpty,
+			NilSlicesAreEmpty:       c.NilSlicesAreEmpty,
+			UseEqualMethods:         c.UseEqualMethods,
+			IgnorePaths:             nonNil(c.IgnorePaths),
+			IgnoreTypes:             typeNames(c.IgnoreTypes),
+			UnorderedSlices:         c.UnorderedSlices,
+			UnorderedTypes:          typeNames(c.UnorderedTypes),
+			UnorderedPaths:          nonNil(c.UnorderedPaths),
+			DeepMapKeys:             c.DeepMapKeys,
+			CompareSlicesByIndex:    c.CompareSlicesByIndex,
+			TextDiffLength:          c.TextDiffLength,
+			TextDiffContext:         c.TextDiffContext,
+			Overrides:               []string{},
+			Color:                   c.Color.String(),
+			Funcs:                   []string{},
+			Keys:                    map[string]string{},
+		},
+		Diffs: r.Diffs,
+	}
+	for _, o := range c.Overrides {
+		rep.Settings.Overrides = append(rep.Settings.Overrides, o.Path)
+	}
+	for t := range c.funcs {
+		rep.Settings.Funcs = append(rep.Settings.Funcs, t.String())
+	}
+	sort.Strings(rep.Settings.Funcs)
+	for t, key := range c.keys {
+		rep.Settings.Keys[t.String()] = key.name
+	}
+	if rep.Diffs == nil {
+		rep.Diffs = []Diff{}
+	}
+	return json.Marshal(rep)
+}
+
+// jsonDiff is the JSON encoding of a Diff.
+type jsonDiff struct {
+	Kind   string     `json:"kind"`
+	Path   string     `json:"path"`
+	Steps  []jsonStep `json:"steps"`
+	Left   *jsonValue `json:"left"`
+	Right  *jsonValue `json:"right"`
+	Delta  *float64   `json:"delta,omitempty"`
+	Detail string     `json:"detail,omitempty"`
+}
+
+// jsonStep is the JSON encoding of a Step.
+type jsonStep struct {
+	Kind  string  `json:"kind"`
+	Type  *string `json:"type"`
+	Name  string  `json:"name,omitempty"`
+	Index *int    `json:"index,omitempty"`
+	Key   *string `json:"key,omitempty"`
+}
+
+// jsonValue is the JSON encoding of one side of a Diff.
+type jsonValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// The diff is encoded as an object with the following members:
+//
+//   - "kind": the string representation of the Kind.
+//   - "path": the string representation of the Path.
+//   - "steps": an array of objects, one for each step of the path. Each has
+//     a "kind" and "type", and, depending on the kind of step, a "name",
+//     "index", and "key", rendered as a string.
+//   - "left", "right": objects containing the "type" and rendered "value" of
+//     each side, or null if the side is absent.
+//   - "delta": the Delta, if it is finite and non-zero.
+//   - "detail": the Detail, if present.
+//
+// As with String, the rendering of values is not guaranteed to be consistent.
+func (d Diff) MarshalJSON() ([]byte, error) {
+	jd := jsonDiff{
+		Kind:   d.Kind.String(),
+		Path:   d.Path.String(),
+		Steps:  make([]jsonStep, len(d.Path)),
+		Left:   renderValue(d.Left),
+		Right:  renderValue(d.Right),
+		Detail: d.Detail,
+	}
+	for i, step := range d.Path {
+		js := jsonStep{Kind: step.Kind.String(), Type: typeName(step.Type)}
+		switch step.Kind {
+		case FieldStep, IndexStep, KeyedStep:
+			index := step.Index
+			js.Index = &index
+		}
+		switch step.Kind {
+		case FieldStep, KeyedStep:
+			js.Name = step.Name
+		}
+		switch step.Kind {
+		case MapKeyStep, KeyedStep:
+			key := formatValue(step.Key)
+			js.Key = &key
+		}
+		jd.Steps[i] = js
+	}
+	if d.Delta != 0 && !math.IsInf(d.Delta, 0) && !math.IsNaN(d.Delta) {
+		delta := d.Delta
+		jd.Delta = &delta
+	}
+	return json.Marshal(jd)
+}
+
+// renderValue returns the encoding of v, or nil if v is invalid.
+func renderValue(v reflect.Value) *jsonValue {
+	if !v.IsValid() {
+		return nil
+	}
+	return &jsonValue{Type: v.Type().String(), Value: formatValue(v)}
+}
+
+// typeName returns the name of t, or nil if t is nil.
+func typeName(t reflect.Type) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.String()
+	return &s
+}
+
+// typeNames returns the names of each type in ts.
+func typeNames(ts []reflect.Type) []string {
+	s := make([]string, len(ts))
+	for i, t := range ts {
+		s[i] = t.String()
+	}
+	return s
+}
+
+// nonNil returns s, or an empty slice if s is nil.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,88 @@
+package deep
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffMarshalJSON(t *testing.T) {
+	c := newComparer("MaxDiffs", 100)
+	diffs := c.Equal(
+		map[string][]basic{"a": {{1, 2}}},
+		map[string][]basic{"a": {{1, 3}}, "b": nil},
+	)
+	if len(diffs) != 2 {
+		t.Fatalf("want 2 diffs, got %v", diffs)
+	}
+	got, err := json.Marshal(diffs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[` +
+		`{"kind":"ValueMismatch","path":"map[a][0].Y","steps":[` +
+		`{"kind":"MapKey","type":"map[string][]deep.basic","key":"a"},` +
+		`{"kind":"Index","type":"[]deep.basic","index":0},` +
+		`{"kind":"Field","type":"deep.basic","name":"Y","index":1}],` +
+		`"left":{"type":"float32","value":"2"},"right":{"type":"float32","value":"3"},"delta":1},` +
+		`{"kind":"ExtraKey","path":"map[b]","steps":[{"kind":"MapKey","type":"map[string][]deep.basic","key":"b"}],` +
+		`"left":null,"right":{"type":"[]deep.basic","value":"\u003cnil slice\u003e"}}` +
+		`]`
+	if string(got) != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+
+	d := Diff{Kind: ValueMismatch, Left: reflect.ValueOf(math.NaN()), Right: reflect.ValueOf(1.0), Delta: math.NaN(), Detail: "x"}
+	got, err = json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"kind":"ValueMismatch","path":"","steps":[],"left":{"type":"float64","value":"NaN"},"right":{"type":"float64","value":"1"},"detail":"x"}`
+	if string(got) != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestReport(t *testing.T) {
+	c := newComparer("MaxDiffs", 1, "IgnoreTypes", []reflect.Type{reflect.TypeOf(0)})
+	c.RegisterKeyField(reflect.TypeOf(keyedRecord{}), ".ID")
+	c.RegisterFunc(func(a, b string) bool { return true })
+
+	r := c.Report(basic{1, 2}, basic{1, 2})
+	if len(r.Diffs) != 0 || r.Truncated() {
+		t.Errorf("unexpected report %+v", r)
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["equal"] != true || got["left_type"] != "deep.basic" || !reflect.DeepEqual(got["diffs"], []interface{}{}) {
+		t.Errorf("unexpected encoding %s", b)
+	}
+	settings := got["settings"].(map[string]interface{})
+	if settings["max_diffs"] != 1.0 ||
+		!reflect.DeepEqual(settings["ignore_types"], []interface{}{"int"}) ||
+		!reflect.DeepEqual(settings["ignore_paths"], []interface{}{}) ||
+		!reflect.DeepEqual(settings["funcs"], []interface{}{"string"}) ||
+		!reflect.DeepEqual(settings["keys"], map[string]interface{}{"deep.keyedRecord": "ID"}) {
+		t.Errorf("unexpected settings %s", b)
+	}
+
+	r = c.Report(nil, []float64{1, 2})
+	if !r.Truncated() || r.LeftType != nil {
+		t.Errorf("unexpected report %+v", r)
+	}
+	b, err = json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); !strings.HasPrefix(s, `{"equal":false,"truncated":true,"left_type":null,"right_type":"[]float64",`) {
+		t.Errorf("unexpected encoding %s", b)
+	}
+}
@@ -0,0 +1,85 @@
+package deep
+
+import (
+	"bytes"
+	"reflect"
+	"unsafe"
+)
+
+// plain returns whether elements of type t, located at depth, can be compared
+// directly, without visiting each element. This is the case for booleans,
+// integers, and strings, unless the configuration may compare them
+// differently.
+func (s *compareState) plain(t reflect.Type, depth int) bool {
+	plan := s.plan(t)
+	if !plan.scalar {
+		return false
+	}
+	if s.MaxDepth > 0 && depth > s.MaxDepth {
+		return false
+	}
+	if len(s.ignorePaths) > 0 || len(s.overridePaths) > 0 {
+		// The paths of elements would have to be built to be matched.
+		return false
+	}
+	if plan.ignored || plan.hasFunc {
+		return false
+	}
+	if s.UseEqualMethods && plan.hasEqual {
+		return false
+	}
+	return true
+}
+
+// plainEqual returns whether x and y, of a type for which plain returns true,
+// are equal.
+func plainEqual(x, y reflect.Value) bool {
+	switch x.Kind() {
+	case reflect.Bool:
+		return x.Bool() == y.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return x.Int() == y.Int()
+	case reflect.String:
+		return x.String() == y.String()
+	}
+	return x.Uint() == y.Uint()
+}
+
+// sameMemory returns whether arrays or slices x and y, with elements of a
+// type for which plain returns true, have the same length and identical
+// contents. Returns false for ok if the contents could not be compared in
+// memory, in which case the elements must be compared individually.
+func sameMemory(x, y reflect.Value) (eq, ok bool) {
+	if x.Type().Elem().Kind() == reflect.String {
+		// Strings with equal contents may have different data pointers.
+		return false, false
+	}
+	if x.Len() != y.Len() {
+		return false, true
+	}
+	px, ok := dataPointer(x)
+	if !ok {
+		return false, false
+	}
+	py, ok := dataPointer(y)
+	if !ok {
+		return false, false
+	}
+	n := x.Len() * int(x.Type().Elem().Size())
+	if n == 0 || px == py {
+		return true, true
+	}
+	return bytes.Equal(unsafe.Slice((*byte)(px), n), unsafe.Slice((*byte)(py), n)), true
+}
+
+// dataPointer returns a pointer to the first element of array or slice v.
+// Returns false if v is an array that is not addressable.
+func dataPointer(v reflect.Value) (unsafe.Pointer, bool) {
+	if v.Kind() == reflect.Slice {
+		return v.UnsafePointer(), true
+	}
+	if !v.CanAddr() {
+		return nil, false
+	}
+	return v.Addr().UnsafePointer(), true
+}
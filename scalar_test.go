@@ -0,0 +1,75 @@
+package deep
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type parity int
+
+func (p parity) Equal(q parity) bool { return p%2 == q%2 }
+
+func TestPlainSequences(t *testing.T) {
+	array := func(v ...int) *[4]int {
+		var a [4]int
+		copy(a[:], v)
+		return &a
+	}
+	tests := []struct {
+		c    Comparer
+		x, y interface{}
+		want []string
+	}{
+		{newComparer(), []int{1, 2, 3}, []int{1, 2, 3}, nil},
+		{newComparer(), []int{1, 2, 3}, []int{1, 5, 3}, []string{"slice[1]: 2 != 5"}},
+		{newComparer(), []int{1, 2, 3}, []int{1, 3}, []string{"slice[1]: 2 != <no value>"}},
+		{newComparer(), []string{"a", "b"}, []string{"a", "c"}, []string{"slice[1]: b != c"}},
+		{newComparer(), []bool{true, false}, []bool{true, true}, []string{"slice[1]: false != true"}},
+		{newComparer(), array(1, 2), array(1, 3), []string{"array[1]: 2 != 3"}},
+		{newComparer(), [2]uint8{1, 2}, [2]uint8{1, 3}, []string{"array[1]: 2 != 3"}},
+		{newComparer("CompareSlicesByIndex", true), []int{1, 2, 3}, []int{1, 3}, []string{"slice[1]: 2 != 3", "slice[2]: 3 != <no value>"}},
+		{newComparer("CompareSlicesByIndex", true), array(1, 2), array(1, 3), []string{"array[1]: 2 != 3"}},
+		{newComparer("IgnoreTypes", []reflect.Type{reflect.TypeOf(0)}), []int{1, 2}, []int{3, 4}, nil},
+		{newComparer("IgnorePaths", []string{"[1]"}), []int{1, 2}, []int{1, 4}, nil},
+		{newComparer("MaxDepth", 1), [][]int{{1}}, [][]int{{2}}, nil},
+		{newComparer("UseEqualMethods", true), []parity{1, 2}, []parity{3, 4}, nil},
+		{func() Comparer {
+			c := newComparer()
+			c.RegisterFunc(func(x, y int) bool { return true })
+			return c
+		}(), []int{1, 2}, []int{3, 4}, nil},
+	}
+	for i, test := range tests {
+		if got := diffStrings(test.c.Equal(test.x, test.y)); len(got) != len(test.want) || len(got) > 0 && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("[%d]: want %q, got %q", i, test.want, got)
+		}
+		if got := test.c.Equivalent(test.x, test.y); got != (test.want == nil) {
+			t.Errorf("[%d]: want equivalent %t, got %t", i, test.want == nil, got)
+		}
+	}
+}
+
+func TestSameMemory(t *testing.T) {
+	tests := []struct {
+		x, y   interface{}
+		eq, ok bool
+	}{
+		{[]int{1, 2}, []int{1, 2}, true, true},
+		{[]int{1, 2}, []int{1, 3}, false, true},
+		{[]int{1, 2}, []int{1}, false, true},
+		{[]int(nil), []int{}, true, true},
+		{&[2]int{1, 2}, &[2]int{1, 2}, true, true},
+		{[2]int{1, 2}, [2]int{1, 2}, false, false},
+		{[]string{"a"}, []string{strings.Repeat("a", 1)}, false, false},
+	}
+	for i, test := range tests {
+		x, y := reflect.ValueOf(test.x), reflect.ValueOf(test.y)
+		if x.Kind() == reflect.Ptr {
+			x, y = x.Elem(), y.Elem()
+		}
+		if eq, ok := sameMemory(x, y); eq != test.eq || ok != test.ok {
+			t.Errorf("[%d]: want (%t, %t), got (%t, %t)", i, test.eq, test.ok, eq, ok)
+		}
+	}
+}
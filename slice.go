@@ -0,0 +1,510 @@
+package deep
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// sliceKey extracts the identity key of an element of a slice.
+type sliceKey struct {
+	// name is the name of the key, as displayed in a path.
+	name string
+	// fn is the function registered with RegisterKey.
+	fn reflect.Value
+	// field is the index sequence of the field registered with
+	// RegisterKeyField.
+	field [][]int
+}
+
+// RegisterKey registers a function that returns the identity key of an
+// element of a slice. The function must have the form
+//
+//	func(T) K
+//
+// where T is the element type, and K is a comparable type. Slices of T are
+// compared by matching elements that have equal keys, then comparing each
+// matched pair. Elements without a match are reported as a MissingElement or
+// ExtraElement. The elements of such slices are located in a path by a
+// KeyedStep, which is displayed as "[key=K]".
+//
+// Registering a key for a type replaces any key previously registered for
+// the type. RegisterKey panics if fn does not have a valid form.
+//
+// Slices whose elements cannot be exported, such as those obtained through
+// unexported fields of values within maps or interfaces, are compared
+// normally.
+func (c *Comparer) RegisterKey(fn interface{}) {
+	if fn == nil {
+		panic("deep: RegisterKey: nil function")
+	}
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func ||
+		t.NumIn() != 1 ||
+		t.NumOut() != 1 ||
+		!t.Out(0).Comparable() ||
+		t.IsVariadic() ||
+		v.IsNil() {
+		panic(fmt.Sprintf("deep: RegisterKey: invalid function type %s", t))
+	}
+	c.registerKey(t.In(0), sliceKey{name: "key", fn: v})
+}
+
+// RegisterKeyField registers a field as the identity key of elements of type
+// t, in the same manner as RegisterKey. field is a sequence of field names as
+// they appear in a path, such as ".ID" or ".Meta.Name". t must be a struct
+// type, or a pointer to a struct type, and the final field must be of a
+// comparable type. Fields are selected through pointers; a nil pointer
+// results in a nil key. The elements of slices of t are displayed in a path
+// as "[ID=K]", using the field names without the leading dot.
+//
+// RegisterKeyField panics if the field cannot be found, or is not
+// comparable.
+func (c *Comparer) RegisterKeyField(t reflect.Type, field string) {
+	if t == nil || !strings.HasPrefix(field, ".") {
+		panic(fmt.Sprintf("deep: RegisterKeyField: invalid field %q", field))
+	}
+	key := sliceKey{name: field[1:]}
+	ft := t
+	for _, name := range strings.Split(field[1:], ".") {
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("deep: RegisterKeyField: field %q of %s: %s is not a struct", field, t, ft))
+		}
+		f, ok := ft.FieldByName(name)
+		if !ok {
+			panic(fmt.Sprintf("deep: RegisterKeyField: %s has no field %q", ft, name))
+		}
+		key.field = append(key.field, f.Index)
+		ft = f.Type
+	}
+	if !ft.Comparable() {
+		panic(fmt.Sprintf("deep: RegisterKeyField: field %q of %s is not comparable", field, t))
+	}
+	c.registerKey(t, key)
+}
+
+// registerKey maps t to key, without affecting copies of the Comparer.
+func (c *Comparer) registerKey(t reflect.Type, key sliceKey) {
+	keys := make(map[reflect.Type]sliceKey, len(c.keys)+1)
+	for k, v := range c.keys {
+		keys[k] = v
+	}
+	keys[t] = key
+	c.keys = keys
+}
+
+// keyOf returns the identity key of v. Returns false if the key could not be
+// determined, or if the key contains a value that cannot be compared, such as
+// a slice within an interface.
+func (k sliceKey) keyOf(v reflect.Value) (interface{}, bool) {
+	v, ok := exportValue(v)
+	if !ok {
+		return nil, false
+	}
+	if k.fn.IsValid() {
+		v = k.fn.Call([]reflect.Value{v})[0]
+		if !hashable(v) {
+			return nil, false
+		}
+		return v.Interface(), true
+	}
+	for _, index := range k.field {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, true
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByIndex(index)
+	}
+	if v, ok = exportValue(v); !ok || !hashable(v) {
+		return nil, false
+	}
+	return v.Interface(), true
+}
+
+// hashable returns whether v can be used as a map key. Unlike the
+// comparability of a type, this includes the dynamic values of interfaces.
+func hashable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface:
+		return v.IsNil() || hashable(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !hashable(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !hashable(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return v.Type().Comparable()
+}
+
+// equivalent returns whether x and y are equivalent, without recording any
+// differences.
+func (s *compareState) equivalent(x, y reflect.Value, depth int) bool {
+	ndiffs, quiet, maxDiffs, probed := s.ndiffs, s.quiet, s.MaxDiffs, len(s.probed)
+	s.ndiffs, s.quiet, s.MaxDiffs = 0, true, 1
+	s.probes++
+	s.deepValueEqual(x, y, depth)
+	eq := s.ndiffs == 0
+	s.probes--
+	// Forget visits, so that the values are compared again if they are
+	// compared for real.
+	for _, v := range s.probed[probed:] {
+		delete(s.visited, v)
+	}
+	s.probed = s.probed[:probed]
+	s.ndiffs, s.quiet, s.MaxDiffs = ndiffs, quiet, maxDiffs
+	return eq
+}
+
+// recheck returns whether elements matched by equivalent must be compared
+// again, because values below MaxDepth are reported only outside of probes.
+func (s *compareState) recheck() bool {
+	return s.ReportMaxDepth && s.MaxDepth > 0 && s.probes == 0
+}
+
+// unordered returns whether the slice x, located at the current path, is to
+// be compared without regard to order.
+func (s *compareState) unordered(x reflect.Value) bool {
+	if s.UnorderedSlices {
+		return true
+	}
+	for _, t := range s.UnorderedTypes {
+		if x.Type() == t {
+			return true
+		}
+	}
+	for _, p := range s.unorderedPaths {
+		if p.match(s.stack) {
+			return true
+		}
+	}
+	return false
+}
+
+// unorderedEqual compares slices x and y as multisets. Elements are paired
+// by a maximum matching between equivalent elements of x and y, so that the
+// result does not depend on the order of either slice. Elements of x without
+// a match are reported as missing, and elements of y without a match are
+// reported as extra.
+func (s *compareState) unorderedEqual(x, y reflect.Value, depth int) bool {
+	// Equivalence is memoized, since finding a maximum matching may consider
+	// the same pair more than once.
+	known := map[[2]int]bool{}
+	equiv := func(i, j int) bool {
+		if eq, ok := known[[2]int{i, j}]; ok {
+			return eq
+		}
+		s.push(Step{Kind: IndexStep, Type: x.Type(), Index: i})
+		eq := s.equivalent(x.Index(i), y.Index(j), depth+1)
+		s.pop()
+		known[[2]int{i, j}] = eq
+		return eq
+	}
+
+	// Match each element of x with the first unmatched equivalent element of
+	// y, which usually matches everything.
+	xmatch := make([]int, x.Len())
+	ymatch := make([]int, y.Len())
+	for j := range ymatch {
+		ymatch[j] = -1
+	}
+	unmatched := 0
+	for i := range xmatch {
+		xmatch[i] = -1
+		for j := range ymatch {
+			if ymatch[j] < 0 && equiv(i, j) {
+				xmatch[i], ymatch[j] = j, i
+				break
+			}
+		}
+		if xmatch[i] < 0 {
+			unmatched++
+		}
+		if s.full() {
+			return false
+		}
+	}
+
+	// A greedy match may pair an element with one needed by another. While
+	// elements of both x and y remain unmatched, search for augmenting paths
+	// that rearrange the matches to include more elements.
+	if unmatched > 0 && unmatched > x.Len()-y.Len() {
+		var visited []bool
+		var augment func(i int) bool
+		augment = func(i int) bool {
+			for j := range ymatch {
+				if visited[j] || !equiv(i, j) {
+					continue
+				}
+				visited[j] = true
+				if ymatch[j] < 0 || augment(ymatch[j]) {
+					xmatch[i], ymatch[j] = j, i
+					return true
+				}
+			}
+			return false
+		}
+		for i := range xmatch {
+			if xmatch[i] >= 0 {
+				continue
+			}
+			visited = make([]bool, y.Len())
+			augment(i)
+			if s.full() {
+				return false
+			}
+		}
+	}
+
+	var missing []int
+	eq := true
+	for i, j := range xmatch {
+		if j < 0 {
+			missing = append(missing, i)
+		} else if s.recheck() && !s.recompare(x, y, i, j, depth) {
+			eq = false
+		}
+		if s.full() {
+			return false
+		}
+	}
+
+	if len(missing) > 0 {
+		eq = false
+	}
+	for _, i := range missing {
+		s.push(Step{Kind: IndexStep, Type: x.Type(), Index: i})
+		if !s.ignore(x.Index(i), reflect.Value{}) {
+			s.append(MissingElement, x.Index(i), reflect.Value{})
+		}
+		s.pop()
+		if s.full() {
+			return false
+		}
+	}
+	for j, i := range ymatch {
+		if i >= 0 {
+			continue
+		}
+		eq = false
+		s.push(Step{Kind: IndexStep, Type: y.Type(), Index: j})
+		if !s.ignore(reflect.Value{}, y.Index(j)) {
+			s.append(ExtraElement, reflect.Value{}, y.Index(j))
+		}
+		s.pop()
+		if s.full() {
+			return false
+		}
+	}
+	return eq
+}
+
+// sequenceEqual compares arrays or slices x and y using a minimal edit script.
+// Runs of elements removed from x and added to y are paired up and compared
+// as modifications, while any remaining elements are reported as missing or
+// extra.
+func (s *compareState) sequenceEqual(x, y reflect.Value, depth int) bool {
+	equal := func(i, j int) bool {
+		s.push(Step{Kind: IndexStep, Type: y.Type(), Index: j})
+		eq := s.equivalent(x.Index(i), y.Index(j), depth+1)
+		s.pop()
+		return eq
+	}
+	if s.plain(x.Type().Elem(), depth+1) {
+		equal = func(i, j int) bool { return plainEqual(x.Index(i), y.Index(j)) }
+	}
+	// Skip the common prefix without building an edit script, since it is
+	// usually the entire sequence.
+	n, m := x.Len(), y.Len()
+	pre := 0
+	for pre < n && pre < m && equal(pre, pre) {
+		pre++
+	}
+	if s.quiet && (pre < n || pre < m) && !s.ignoring() {
+		// Any remaining element produces a difference, so there is no need
+		// to find which. The difference is counted without being located.
+		s.ndiffs++
+		return false
+	}
+	var script []edit
+	suf := 0
+	if pre < n || pre < m {
+		// Each run of edits produces at least one difference for every two
+		// edits, so a script with more than twice the number of remaining
+		// differences would be truncated by MaxDiffs regardless. Ignored
+		// elements produce no differences, so the search is not limited when
+		// they may exist.
+		limit := 0
+		if s.MaxDiffs > 0 && !s.ignoring() {
+			limit = 2 * (s.MaxDiffs - s.ndiffs)
+		}
+		var more int
+		script, more, suf = editScript(n-pre, m-pre, limit, func(i, j int) bool { return equal(pre+i, pre+j) })
+		for k := range script {
+			script[k].x += pre
+			script[k].y += pre
+		}
+		pre += more
+	}
+
+	eq := true
+	if s.recheck() {
+		for i := 0; i < pre; i++ {
+			if !s.recompare(x, y, i, i, depth) {
+				eq = false
+			}
+			if s.full() {
+				return false
+			}
+		}
+	}
+
+	var dels, ins []int
+	for k := 0; k < len(script); {
+		if script[k].op == editMatch {
+			if s.recheck() && !s.recompare(x, y, script[k].x, script[k].y, depth) {
+				eq = false
+			}
+			if s.full() {
+				return false
+			}
+			k++
+			continue
+		}
+		dels, ins = dels[:0], ins[:0]
+		for ; k < len(script) && script[k].op != editMatch; k++ {
+			if script[k].op == editDelete {
+				dels = append(dels, script[k].x)
+			} else {
+				ins = append(ins, script[k].y)
+			}
+		}
+		n := len(dels)
+		if len(ins) < n {
+			n = len(ins)
+		}
+		for p := 0; p < n; p++ {
+			s.push(Step{Kind: IndexStep, Type: y.Type(), Index: ins[p]})
+			if !s.deepValueEqual(x.Index(dels[p]), y.Index(ins[p]), depth+1) {
+				eq = false
+			}
+			s.pop()
+			if s.full() {
+				return false
+			}
+		}
+		for _, i := range dels[n:] {
+			eq = false
+			s.push(Step{Kind: IndexStep, Type: x.Type(), Index: i})
+			if !s.ignore(x.Index(i), reflect.Value{}) {
+				s.append(MissingElement, x.Index(i), reflect.Value{})
+			}
+			s.pop()
+			if s.full() {
+				return false
+			}
+		}
+		for _, j := range ins[n:] {
+			eq = false
+			s.push(Step{Kind: IndexStep, Type: y.Type(), Index: j})
+			if !s.ignore(reflect.Value{}, y.Index(j)) {
+				s.append(ExtraElement, reflect.Value{}, y.Index(j))
+			}
+			s.pop()
+			if s.full() {
+				return false
+			}
+		}
+	}
+	if s.recheck() {
+		for k := 0; k < suf; k++ {
+			if !s.recompare(x, y, n-suf+k, m-suf+k, depth) {
+				eq = false
+			}
+			if s.full() {
+				return false
+			}
+		}
+	}
+	return eq
+}
+
+// recompare compares element i of x with element j of y, which were matched
+// by equivalent.
+func (s *compareState) recompare(x, y reflect.Value, i, j, depth int) bool {
+	s.push(Step{Kind: IndexStep, Type: y.Type(), Index: j})
+	defer s.pop()
+	return s.deepValueEqual(x.Index(i), y.Index(j), depth+1)
+}
+
+// keyedEqual compares slices x and y by matching elements with equal identity
+// keys. Returns false for ok if the keys could not be determined.
+func (s *compareState) keyedEqual(x, y reflect.Value, key sliceKey, depth int) (eq, ok bool) {
+	xkeys := make([]interface{}, x.Len())
+	for i := range xkeys {
+		if xkeys[i], ok = key.keyOf(x.Index(i)); !ok {
+			return false, false
+		}
+	}
+	ykeys := make([]interface{}, y.Len())
+	yindex := make(map[interface{}][]int, y.Len())
+	for j := range ykeys {
+		if ykeys[j], ok = key.keyOf(y.Index(j)); !ok {
+			return false, false
+		}
+		yindex[ykeys[j]] = append(yindex[ykeys[j]], j)
+	}
+
+	eq = true
+	matched := make([]bool, y.Len())
+	for i, k := range xkeys {
+		if js := yindex[k]; len(js) > 0 {
+			j := js[0]
+			yindex[k] = js[1:]
+			matched[j] = true
+			s.push(Step{Kind: KeyedStep, Type: y.Type(), Name: key.name, Index: i, Key: reflect.ValueOf(k)})
+			if !s.deepValueEqual(x.Index(i), y.Index(j), depth+1) {
+				eq = false
+			}
+		} else {
+			eq = false
+			s.push(Step{Kind: KeyedStep, Type: x.Type(), Name: key.name, Index: i, Key: reflect.ValueOf(k)})
+			if !s.ignore(x.Index(i), reflect.Value{}) {
+				s.append(MissingElement, x.Index(i), reflect.Value{})
+			}
+		}
+		s.pop()
+		if s.full() {
+			return false, true
+		}
+	}
+	for j, ok := range matched {
+		if ok {
+			continue
+		}
+		eq = false
+		s.push(Step{Kind: KeyedStep, Type: y.Type(), Name: key.name, Index: j, Key: reflect.ValueOf(ykeys[j])})
+		if !s.ignore(reflect.Value{}, y.Index(j)) {
+			s.append(ExtraElement, reflect.Value{}, y.Index(j))
+		}
+		s.pop()
+		if s.full() {
+			return false, true
+		}
+	}
+	return eq, true
+}
@@ -0,0 +1,269 @@
+package deep
+
+import (
+	"reflect"
+	"testing"
+)
+
+type unorderedRecord struct {
+	Name    string
+	Members []string
+	Tags    []string
+}
+
+func diffStrings(diffs []Diff) []string {
+	s := make([]string, len(diffs))
+	for i, d := range diffs {
+		s[i] = d.String()
+	}
+	return s
+}
+
+func TestUnorderedSlices(t *testing.T) {
+	x := []interface{}{1, "a", 2.5, []int{1, 2}, 1}
+	y := []interface{}{[]int{1, 2}, 1, 2.5, "a", 1}
+
+	c := newComparer("MaxDiffs", 100)
+	if diffs := c.Equal(x, y); len(diffs) == 0 {
+		t.Errorf("want diffs when ordered")
+	}
+	c.UnorderedSlices = true
+	if diffs := c.Equal(x, y); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+
+	diffs := c.Equal([]int{1, 2, 2, 3}, []int{4, 2, 1, 1})
+	want := []string{
+		"slice[2]: 2 != <no value>",
+		"slice[3]: 3 != <no value>",
+		"slice[0]: <no value> != 4",
+		"slice[3]: <no value> != 1",
+	}
+	if got := diffStrings(diffs); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if diffs[0].Kind != MissingElement || diffs[2].Kind != ExtraElement {
+		t.Errorf("unexpected kinds %s, %s", diffs[0].Kind, diffs[2].Kind)
+	}
+
+	// Elements are matched using the configured tolerances.
+	if diffs := c.Equal([]float64{0.1, 0.2}, []float64{0.2000000000001, 0.1}); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+
+	// Nested slices are compared with their own ordering.
+	if diffs := c.Equal([][]int{{1, 2}, {3}}, [][]int{{3}, {2, 1}}); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+
+	// The result does not depend on which match is found first.
+	c.FloatAbsTolerance = 0.7
+	for _, y := range [][]float64{{0.6, 1.4}, {1.4, 0.6}} {
+		if diffs := c.Equal([]float64{1.0, 0.0}, y); diffs != nil {
+			t.Errorf("%v: want no diffs, got %v", y, diffs)
+		}
+		if !c.Equivalent([]float64{1.0, 0.0}, y) {
+			t.Errorf("%v: want equivalent", y)
+		}
+	}
+	want = []string{"slice[1]: 0 != <no value>", "slice[1]: <no value> != 2"}
+	if got := diffStrings(c.Equal([]float64{1.0, 0.0, 0.5}, []float64{0.6, 2, 1.1})); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestUnorderedScoped(t *testing.T) {
+	x := unorderedRecord{Name: "a", Members: []string{"x", "y"}, Tags: []string{"1", "2"}}
+	y := unorderedRecord{Name: "a", Members: []string{"y", "x"}, Tags: []string{"2", "1"}}
+
+	c := newComparer("MaxDiffs", 100)
+	c.UnorderedPaths = []string{".Members"}
+	if diffs := c.Equal(x, y); len(diffs) != 2 || diffs[0].Path.String() != "struct.Tags[0]" {
+		t.Errorf("want diffs only in Tags, got %v", diffs)
+	}
+
+	c.UnorderedPaths = nil
+	c.UnorderedTypes = []reflect.Type{reflect.TypeOf([]string(nil))}
+	if diffs := c.Equal(x, y); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+}
+
+type cyclicNode struct {
+	Value int
+	Next  []*cyclicNode
+}
+
+func TestUnorderedCycles(t *testing.T) {
+	a := &cyclicNode{Value: 1}
+	a.Next = []*cyclicNode{a, {Value: 2}}
+	b := &cyclicNode{Value: 1}
+	b.Next = []*cyclicNode{{Value: 3}, b}
+
+	c := newComparer("MaxDiffs", 100, "UnorderedSlices", true)
+	diffs := c.Equal(a, b)
+	if len(diffs) != 2 {
+		t.Fatalf("want 2 diffs, got %d: %v", len(diffs), diffs)
+	}
+	if diffs[0].Kind != MissingElement || diffs[1].Kind != ExtraElement {
+		t.Errorf("unexpected diffs %v", diffs)
+	}
+}
+
+func TestSequenceSlices(t *testing.T) {
+	x := make([]int, 100)
+	for i := range x {
+		x[i] = i
+	}
+	y := append([]int{-1}, x...)
+
+	c := newComparer("MaxDiffs", 1000)
+	diffs := c.Equal(x, y)
+	if got := diffStrings(diffs); !reflect.DeepEqual(got, []string{"slice[0]: <no value> != -1"}) {
+		t.Errorf("unexpected diffs %q", got)
+	}
+	diffs = c.Equal(y, x)
+	if got := diffStrings(diffs); !reflect.DeepEqual(got, []string{"slice[0]: -1 != <no value>"}) {
+		t.Errorf("unexpected diffs %q", got)
+	}
+
+	c.CompareSlicesByIndex = true
+	if diffs := c.Equal(x, y); len(diffs) != 101 {
+		t.Errorf("want 101 diffs by index, got %d", len(diffs))
+	}
+
+	c.CompareSlicesByIndex = false
+	diffs = c.Equal(
+		[]basic{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}},
+		[]basic{{1, 1}, {3, 3}, {4, 5}, {5, 5}, {6, 6}},
+	)
+	want := []string{
+		"slice[1]: {2 2} != <no value>",
+		"slice[2].Y: 4 != 5 (delta 1)",
+		"slice[4]: <no value> != {6 6}",
+	}
+	if got := diffStrings(diffs); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	// Arrays have a fixed length, so they are compared by index.
+	diffs = c.Equal([4]string{"a", "b", "c", "d"}, [4]string{"b", "c", "d", "e"})
+	want = []string{
+		"array[0]: a != b",
+		"array[1]: b != c",
+		"array[2]: c != d",
+		"array[3]: d != e",
+	}
+	if got := diffStrings(diffs); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+type keyedRecord struct {
+	ID   int
+	Name string
+	Meta *keyedMeta
+}
+
+type keyedMeta struct {
+	Label string
+}
+
+func TestKeyedSlices(t *testing.T) {
+	x := []keyedRecord{{ID: 1, Name: "a"}, {ID: 42, Name: "b"}, {ID: 3, Name: "c"}}
+	y := []keyedRecord{{ID: 42, Name: "B"}, {ID: 4, Name: "d"}, {ID: 1, Name: "a"}}
+
+	c := newComparer("MaxDiffs", 100)
+	c.RegisterKeyField(reflect.TypeOf(keyedRecord{}), ".ID")
+	diffs := c.Equal(x, y)
+	want := []string{
+		"slice[ID=42].Name: b != B",
+		"slice[ID=3]: {3 c <nil>} != <no value>",
+		"slice[ID=4]: <no value> != {4 d <nil>}",
+	}
+	if got := diffStrings(diffs); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if d := diffs[0].Path[0]; d.Kind != KeyedStep || d.Index != 1 || d.Key.Interface() != 42 {
+		t.Errorf("unexpected step %+v", d)
+	}
+	if d := diffs[1].Path[0]; d.Index != 2 {
+		t.Errorf("want index of missing element 2, got %d", d.Index)
+	}
+
+	c.IgnorePaths = []string{"[ID=42].Name", "[ID=3]", "[ID=4]"}
+	if diffs := c.Equal(x, y); diffs != nil {
+		t.Errorf("want no diffs, got %v", diffs)
+	}
+
+	// Nested keys through pointers, nil pointers having a nil key.
+	c = newComparer("MaxDiffs", 100)
+	c.RegisterKeyField(reflect.TypeOf(&keyedRecord{}), ".Meta.Label")
+	px := []*keyedRecord{{ID: 1, Meta: &keyedMeta{"a"}}, nil, {ID: 2}}
+	py := []*keyedRecord{{ID: 3}, {ID: 1, Meta: &keyedMeta{"a"}}}
+	want = []string{
+		"slice[Meta.Label=<nil>]: <nil> != &{3  <nil>}",
+		"slice[Meta.Label=<nil>]: &{2  <nil>} != <no value>",
+	}
+	if got := diffStrings(c.Equal(px, py)); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	// Key functions.
+	c = newComparer("MaxDiffs", 100)
+	c.RegisterKey(func(s string) byte { return s[0] })
+	want = []string{
+		"slice[key=98]: bar != baz",
+	}
+	if got := diffStrings(c.Equal([]string{"foo", "bar"}, []string{"baz", "foo"})); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestKeyedSlicesUnhashable(t *testing.T) {
+	type E struct {
+		K interface{}
+		V int
+	}
+	type pair struct{ A, B interface{} }
+	x := []E{{K: 1, V: 1}, {K: []int{1}, V: 2}}
+	y := []E{{K: 1, V: 1}, {K: []int{1}, V: 3}}
+	want := []string{"slice[1].V: 2 != 3"}
+
+	c := newComparer()
+	c.RegisterKeyField(reflect.TypeOf(E{}), ".K")
+	if got := diffStrings(c.Equal(x, y)); !reflect.DeepEqual(got, want) {
+		t.Errorf("field: want %q, got %q", want, got)
+	}
+	if got := c.TreeDiff(x, y); got == "" {
+		t.Errorf("field: want tree diff")
+	}
+
+	c = newComparer()
+	c.RegisterKey(func(e E) interface{} { return pair{e.V, e.K} })
+	if got := diffStrings(c.Equal(x, y)); !reflect.DeepEqual(got, want) {
+		t.Errorf("func: want %q, got %q", want, got)
+	}
+}
+
+func TestRegisterKeyInvalid(t *testing.T) {
+	for i, fn := range []func(c *Comparer){
+		func(c *Comparer) { c.RegisterKey(nil) },
+		func(c *Comparer) { c.RegisterKey(func(x, y int) int { return 0 }) },
+		func(c *Comparer) { c.RegisterKey(func(x int) []int { return nil }) },
+		func(c *Comparer) { c.RegisterKeyField(reflect.TypeOf(keyedRecord{}), "ID") },
+		func(c *Comparer) { c.RegisterKeyField(reflect.TypeOf(keyedRecord{}), ".Foo") },
+		func(c *Comparer) { c.RegisterKeyField(reflect.TypeOf(keyedRecord{}), ".Name.Foo") },
+		func(c *Comparer) { c.RegisterKeyField(reflect.TypeOf(unorderedRecord{}), ".Tags") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("[%d]: expected panic", i)
+				}
+			}()
+			var c Comparer
+			fn(&c)
+		}()
+	}
+}
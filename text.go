@@ -0,0 +1,135 @@
+package deep
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// appendString appends a ValueMismatch between strings x and y, describing
+// the difference in detail according to the configuration.
+func (s *compareState) appendString(x, y reflect.Value) {
+	s.append(ValueMismatch, x, y)
+	if s.TextDiffLength <= 0 || s.quiet {
+		return
+	}
+	vx, vy := x.String(), y.String()
+	d := &s.result[len(s.result)-1]
+	if len(vx) >= s.TextDiffLength || len(vy) >= s.TextDiffLength ||
+		strings.Contains(vx, "\n") || strings.Contains(vy, "\n") {
+		d.Detail = unifiedDiff(vx, vy, s.TextDiffContext)
+		return
+	}
+	d.Detail = fmt.Sprintf("first difference at byte %d", firstDifference(vx, vy))
+}
+
+// firstDifference returns the offset of the first byte that differs between
+// a and b.
+func firstDifference(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// unifiedDiff returns a line-based diff between a and b, in the unified
+// format. Each hunk includes up to context unchanged lines around the changed
+// lines.
+func unifiedDiff(a, b string, context int) string {
+	if context < 0 {
+		context = 0
+	}
+	al, bl := strings.Split(a, "\n"), strings.Split(b, "\n")
+	middle, pre, suf := editScript(len(al), len(bl), 0, func(i, j int) bool { return al[i] == bl[j] })
+	// Only the unchanged lines that may be included as context are needed from
+	// the common prefix and suffix.
+	skip := pre - context
+	if skip < 0 {
+		skip = 0
+	}
+	if suf > context {
+		suf = context
+	}
+	script := make([]edit, 0, pre-skip+len(middle)+suf)
+	for i := skip; i < pre; i++ {
+		script = append(script, edit{op: editMatch, x: i, y: i})
+	}
+	script = append(script, middle...)
+	for k := 0; k < suf; k++ {
+		script = append(script, edit{op: editMatch, x: len(al) - suf + k, y: len(bl) - suf + k})
+	}
+
+	// Position within each sequence before each edit.
+	xpos := make([]int, len(script)+1)
+	ypos := make([]int, len(script)+1)
+	xpos[0], ypos[0] = skip, skip
+	for k, e := range script {
+		xpos[k+1], ypos[k+1] = xpos[k], ypos[k]
+		if e.op != editInsert {
+			xpos[k+1]++
+		}
+		if e.op != editDelete {
+			ypos[k+1]++
+		}
+	}
+
+	var buf strings.Builder
+	buf.WriteString("--- left\n+++ right")
+	for start := 0; start < len(script); {
+		for start < len(script) && script[start].op == editMatch {
+			start++
+		}
+		if start == len(script) {
+			break
+		}
+		// Include following changes that are close enough to share context.
+		last := start
+		for k := start + 1; k < len(script); k++ {
+			if script[k].op == editMatch {
+				continue
+			}
+			if k-last-1 > 2*context {
+				break
+			}
+			last = k
+		}
+		lo := start - context
+		if lo < 0 {
+			lo = 0
+		}
+		hi := last + context + 1
+		if hi > len(script) {
+			hi = len(script)
+		}
+
+		fmt.Fprintf(&buf, "\n@@ -%s +%s @@",
+			hunkRange(xpos[lo], xpos[hi]-xpos[lo]),
+			hunkRange(ypos[lo], ypos[hi]-ypos[lo]),
+		)
+		for _, e := range script[lo:hi] {
+			switch e.op {
+			case editMatch:
+				buf.WriteString("\n " + al[e.x])
+			case editDelete:
+				buf.WriteString("\n-" + al[e.x])
+			case editInsert:
+				buf.WriteString("\n+" + bl[e.y])
+			}
+		}
+		start = hi
+	}
+	return buf.String()
+}
+
+// hunkRange formats the range of lines of a hunk, given the zero-based start
+// and the number of lines.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
@@ -0,0 +1,92 @@
+package deep
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		context int
+		want    string
+	}{
+		{"a\nb\nc", "a\nB\nc", 3, "" +
+			"--- left\n+++ right\n" +
+			"@@ -1,3 +1,3 @@\n" +
+			" a\n" +
+			"-b\n" +
+			"+B\n" +
+			" c",
+		},
+		{"a", "", 0, "" +
+			"--- left\n+++ right\n" +
+			"@@ -1 +1 @@\n" +
+			"-a\n" +
+			"+",
+		},
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9", "0\n1\n2\n3\n4\n5\n6\n7\n9", 1, "" +
+			"--- left\n+++ right\n" +
+			"@@ -1 +1,2 @@\n" +
+			"+0\n" +
+			" 1\n" +
+			"@@ -7,3 +8,2 @@\n" +
+			" 7\n" +
+			"-8\n" +
+			" 9",
+		},
+		{"1\n2\n3\n4\n5", "1\n2\nx\n4\ny", 1, "" +
+			"--- left\n+++ right\n" +
+			"@@ -2,4 +2,4 @@\n" +
+			" 2\n" +
+			"-3\n" +
+			"+x\n" +
+			" 4\n" +
+			"-5\n" +
+			"+y",
+		},
+	}
+	for i, test := range tests {
+		if got := unifiedDiff(test.a, test.b, test.context); got != test.want {
+			t.Errorf("[%d]: want\n%s\ngot\n%s", i, test.want, got)
+		}
+	}
+}
+
+func TestTextDiff(t *testing.T) {
+	c := newComparer("TextDiffLength", 20, "TextDiffContext", 1)
+
+	diffs := c.Equal(basic{}, basic{})
+	if diffs != nil {
+		t.Fatalf("unexpected diffs %v", diffs)
+	}
+
+	type doc struct {
+		Title string
+		Body  string
+	}
+	x := doc{Title: "foobar", Body: "select *\nfrom t\nwhere x = 1\norder by y"}
+	y := doc{Title: "foobaz", Body: "select *\nfrom t\nwhere x = 2\norder by y"}
+	diffs = c.Equal(x, y)
+	if len(diffs) != 2 {
+		t.Fatalf("want 2 diffs, got %v", diffs)
+	}
+	if s := diffs[0].String(); s != "struct.Title: foobar != foobaz (first difference at byte 5)" {
+		t.Errorf("unexpected string %q", s)
+	}
+	want := "struct.Body:\n--- left\n+++ right\n@@ -2,3 +2,3 @@\n from t\n-where x = 1\n+where x = 2\n order by y"
+	if s := diffs[1].String(); s != want {
+		t.Errorf("want\n%s\ngot\n%s", want, s)
+	}
+
+	long := strings.Repeat("a", 20)
+	diffs = c.Equal(long, long+"b")
+	if len(diffs) != 1 || !strings.HasPrefix(diffs[0].Detail, "--- left") {
+		t.Errorf("want line diff for long string, got %v", diffs)
+	}
+
+	c.TextDiffLength = 0
+	if diffs := c.Equal(x, y); diffs[0].Detail != "" || diffs[1].Detail != "" {
+		t.Errorf("want no detail when disabled, got %v", diffs)
+	}
+}
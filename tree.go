@@ -0,0 +1,423 @@
+package deep
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// lineOp indicates how a line of a tree diff relates to the compared values.
+type lineOp int
+
+const (
+	lineContext lineOp = iota // Part of both values.
+	lineRemove                // Part of the left value only.
+	lineAdd                   // Part of the right value only.
+)
+
+// treeLine is a single line of a tree diff.
+type treeLine struct {
+	op     lineOp
+	indent int
+	text   string
+}
+
+// treeNode is a location within the compared values that contains at least
+// one difference.
+type treeNode struct {
+	step     Step
+	diff     *Diff
+	children []*treeNode
+}
+
+// child returns the child of n selected by step, adding it if it does not
+// exist. A final step of a MissingElement or ExtraElement always adds a new
+// child, because its index does not refer to the same value as other steps.
+func (n *treeNode) child(step Step, unique bool) *treeNode {
+	if !unique {
+		for _, c := range n.children {
+			if c.diff == nil && sameStep(c.step, step) {
+				return c
+			}
+		}
+	}
+	c := &treeNode{step: step}
+	n.children = append(n.children, c)
+	return c
+}
+
+// treeRenderer renders the differences between two values as a tree.
+type treeRenderer struct {
+	Comparer
+	lines []treeLine
+	// Pointers being rendered, to detect cycles.
+	seen map[uintptr]bool
+}
+
+// TreeDiff compares x and y, and returns a human-readable rendering of the
+// differences between them. If the values are equivalent, then an empty
+// string is returned. The result is colored only if Color is ColorAlways.
+//
+// The values are rendered as an indented tree in a syntax similar to Go. Only
+// the parts of the tree that contain differences are included. Runs of
+// unchanged fields, elements, and entries are collapsed into a comment. Lines
+// belonging only to the left value are marked with "-", and lines belonging
+// only to the right value are marked with "+". Values nested deeper than
+// MaxDepth are elided. Every difference is rendered, regardless of MaxDiffs,
+// so that no changed value is counted as unchanged. Like Diff.String, the
+// result is not guaranteed to be consistent.
+func (c Comparer) TreeDiff(x, y interface{}) string {
+	var b strings.Builder
+	c.WriteTreeDiff(&b, x, y)
+	return b.String()
+}
+
+// WriteTreeDiff is like TreeDiff, but writes the rendering to w, colored
+// according to the Color setting. Lines of the left value are red, lines of
+// the right value are green, and other lines are dimmed. Nothing is written if
+// the values are equivalent.
+func (c Comparer) WriteTreeDiff(w io.Writer, x, y interface{}) error {
+	c.MaxDiffs = 0
+	diffs := c.Equal(x, y)
+	if diffs == nil {
+		return nil
+	}
+	r := &treeRenderer{Comparer: c, seen: map[uintptr]bool{}}
+	r.node(r.build(diffs), "", "", 0, reflect.ValueOf(y))
+	_, err := io.WriteString(w, r.format(c.styler(w)))
+	return err
+}
+
+// TreeDiff compares x and y using the global configuration, and returns a
+// human-readable rendering of the differences between them.
+func TreeDiff(x, y interface{}) string {
+	return Config.TreeDiff(x, y)
+}
+
+// build returns a tree containing the location of each diff.
+func (r *treeRenderer) build(diffs []Diff) *treeNode {
+	root := &treeNode{}
+	for i := range diffs {
+		d := &diffs[i]
+		n := root
+		for j, step := range d.Path {
+			final := j == len(d.Path)-1
+			n = n.child(step, final && (d.Kind == MissingElement || d.Kind == ExtraElement))
+		}
+		n.diff = d
+	}
+	return root
+}
+
+// format returns the rendered lines, styled by st. Lines of the left value
+// are styled as left values, lines of the right value as right values, and
+// other lines as paths.
+func (r *treeRenderer) format(st styler) string {
+	var b strings.Builder
+	for i, line := range r.lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		text := strings.Repeat("\t", line.indent) + line.text
+		switch line.op {
+		case lineContext:
+			b.WriteString(st.path("  " + text))
+		case lineRemove:
+			b.WriteString(st.left("- " + text))
+		case lineAdd:
+			b.WriteString(st.right("+ " + text))
+		}
+	}
+	return b.String()
+}
+
+// line adds a line to the rendering.
+func (r *treeRenderer) line(op lineOp, indent int, text string) {
+	r.lines = append(r.lines, treeLine{op: op, indent: indent, text: text})
+}
+
+// node renders n, prefixed by label and followed by suffix. y is the value
+// of the right side located at n, which may be invalid if it could not be
+// determined.
+func (r *treeRenderer) node(n *treeNode, label, suffix string, indent int, y reflect.Value) {
+	if n.diff != nil {
+		r.leaf(n.diff, label, suffix, indent)
+		return
+	}
+	if len(n.children) == 0 {
+		return
+	}
+	first := n.children[0].step
+	switch first.Kind {
+	case IndirectStep:
+		r.node(n.children[0], label+"&", suffix, indent, elem(y))
+		return
+	case ElemStep:
+		r.node(n.children[0], label, suffix, indent, elem(y))
+		return
+	}
+
+	r.line(lineContext, indent, label+first.Type.String()+"{")
+	switch first.Kind {
+	case FieldStep:
+		next := 0
+		for _, c := range n.children {
+			r.unchanged(c.step.Index-next, "field", "fields", indent+1)
+			var fy reflect.Value
+			if y.IsValid() {
+				fy = y.Field(c.step.Index)
+			}
+			r.node(c, c.step.Name+": ", ",", indent+1, fy)
+			next = c.step.Index + 1
+		}
+		r.unchanged(first.Type.NumField()-next, "field", "fields", indent+1)
+	case MapKeyStep:
+		// Entries are rendered in the order of their keys, as with values
+		// rendered in full.
+		children := append([]*treeNode(nil), n.children...)
+		sort.SliceStable(children, func(i, j int) bool {
+			return compareKeys(children[i].step.Key, children[j].step.Key) < 0
+		})
+		changed := 0
+		for _, c := range children {
+			var ey reflect.Value
+			if y.IsValid() {
+				ey = y.MapIndex(c.step.Key)
+			}
+			r.node(c, fmt.Sprintf("%#v: ", c.step.Key), ",", indent+1, ey)
+			if c.diff == nil || c.diff.Kind != MissingKey {
+				changed++
+			}
+		}
+		if y.IsValid() {
+			r.unchanged(y.Len()-changed, "entry", "entries", indent+1)
+		}
+	case IndexStep, KeyedStep:
+		var changed []bool
+		if y.IsValid() {
+			changed = make([]bool, y.Len())
+		}
+		pos := r.positions(n.children, y)
+		for k, i := range pos {
+			if i >= 0 && !missing(n.children[k]) {
+				changed[i] = true
+			}
+		}
+		// unchangedTo renders the unchanged elements before position i.
+		next := 0
+		unchangedTo := func(i int) {
+			count := 0
+			for ; next < i; next++ {
+				if !changed[next] {
+					count++
+				}
+			}
+			r.unchanged(count, "element", "elements", indent+1)
+		}
+		for k, c := range n.children {
+			var ey reflect.Value
+			if missing(c) {
+				unchangedTo(pos[k])
+			} else if pos[k] >= 0 {
+				unchangedTo(pos[k])
+				ey = y.Index(pos[k])
+			}
+			r.node(c, "", ",", indent+1, ey)
+		}
+		unchangedTo(len(changed))
+	}
+	r.line(lineContext, indent, "}"+suffix)
+}
+
+// positions returns the index within the right value y of the element of each
+// child, or -1 if it could not be determined. A missing element is given the
+// index of the element of y that it precedes.
+func (r *treeRenderer) positions(children []*treeNode, y reflect.Value) []int {
+	pos := make([]int, len(children))
+	if !y.IsValid() {
+		for k := range pos {
+			pos[k] = -1
+		}
+		return pos
+	}
+	var removed, added []int
+	for k, c := range children {
+		switch {
+		case missing(c):
+			removed = append(removed, c.step.Index)
+		case c.diff != nil && c.diff.Kind == ExtraElement:
+			// The index of an extra element always refers to y.
+			pos[k] = c.step.Index
+			added = append(added, c.step.Index)
+		default:
+			pos[k] = r.position(y, c.step)
+		}
+	}
+	sort.Ints(removed)
+	sort.Ints(added)
+	for k, c := range children {
+		if !missing(c) {
+			continue
+		}
+		// Unchanged elements are in the same order in both values, so the
+		// element follows as many of them in y as it does in x.
+		i := c.step.Index - sort.SearchInts(removed, c.step.Index)
+		for _, j := range added {
+			if j < i {
+				i++
+			}
+		}
+		if i > y.Len() {
+			i = y.Len()
+		}
+		pos[k] = i
+	}
+	return pos
+}
+
+// missing reports whether n is an element of the left value only.
+func missing(n *treeNode) bool {
+	return n.diff != nil && n.diff.Kind == MissingElement
+}
+
+// position returns the index within the right value y of the element selected
+// by step, or -1 if it could not be determined.
+func (r *treeRenderer) position(y reflect.Value, step Step) int {
+	if !y.IsValid() {
+		return -1
+	}
+	if step.Kind == IndexStep {
+		if step.Index < y.Len() {
+			return step.Index
+		}
+		return -1
+	}
+	// The index of a KeyedStep refers to the left value.
+	key, ok := r.keys[y.Type().Elem()]
+	if !ok {
+		return -1
+	}
+	for i := 0; i < y.Len(); i++ {
+		if k, ok := key.keyOf(y.Index(i)); ok && k == step.Key.Interface() {
+			return i
+		}
+	}
+	return -1
+}
+
+// elem returns the value pointed to or contained by v, or an invalid value if
+// it could not be determined.
+func elem(v reflect.Value) reflect.Value {
+	if !v.IsValid() || v.IsNil() {
+		return reflect.Value{}
+	}
+	return v.Elem()
+}
+
+// unchanged renders a comment describing n unchanged values, using the
+// singular or plural noun.
+func (r *treeRenderer) unchanged(n int, singular, plural string, indent int) {
+	switch {
+	case n == 1:
+		r.line(lineContext, indent, "... // 1 unchanged "+singular)
+	case n > 1:
+		r.line(lineContext, indent, fmt.Sprintf("... // %d unchanged %s", n, plural))
+	}
+}
+
+// leaf renders the values of a difference.
+func (r *treeRenderer) leaf(d *Diff, label, suffix string, indent int) {
+	if d.Kind == DepthExceeded {
+		r.line(lineContext, indent, label+"..."+suffix+" // not compared beyond MaxDepth")
+		return
+	}
+	typed := d.Kind == TypeMismatch
+	depth := 0
+	for _, step := range d.Path {
+		if step.Kind != IndirectStep && step.Kind != ElemStep {
+			depth++
+		}
+	}
+	if d.Kind != ExtraKey && d.Kind != ExtraElement {
+		r.value(lineRemove, label, suffix, indent, d.Left, typed, depth)
+	}
+	if d.Kind != MissingKey && d.Kind != MissingElement {
+		r.value(lineAdd, label, suffix, indent, d.Right, typed, depth)
+	}
+}
+
+// value renders v in full, prefixed by label and followed by suffix. If typed
+// is true, then the type of a scalar value is included. depth is the depth of
+// v within the compared values.
+func (r *treeRenderer) value(op lineOp, label, suffix string, indent int, v reflect.Value, typed bool, depth int) {
+	if !v.IsValid() {
+		r.line(op, indent, label+"nil"+suffix)
+		return
+	}
+	t := v.Type()
+	composite := false
+	switch v.Kind() {
+	case reflect.Struct:
+		composite = true
+	case reflect.Array:
+		composite = t.Elem().Kind() != reflect.Uint8
+	case reflect.Slice, reflect.Map:
+		composite = !v.IsNil() && t.Elem().Kind() != reflect.Uint8
+	case reflect.Ptr:
+		if v.IsNil() {
+			r.line(op, indent, label+"("+t.String()+")(nil)"+suffix)
+			return
+		}
+		p := v.Pointer()
+		if r.seen[p] {
+			r.line(op, indent, label+"&"+t.Elem().String()+"{...}"+suffix+" // cycle")
+			return
+		}
+		r.seen[p] = true
+		r.value(op, label+"&", suffix, indent, v.Elem(), typed, depth)
+		delete(r.seen, p)
+		return
+	case reflect.Interface:
+		if v.IsNil() {
+			r.line(op, indent, label+"nil"+suffix)
+			return
+		}
+		r.value(op, label, suffix, indent, v.Elem(), typed, depth)
+		return
+	}
+	if !composite {
+		if typed && v.Kind() != reflect.Slice && v.Kind() != reflect.Map && v.Kind() != reflect.Array {
+			r.line(op, indent, fmt.Sprintf("%s%s(%#v)%s", label, t, v, suffix))
+			return
+		}
+		r.line(op, indent, fmt.Sprintf("%s%#v%s", label, v, suffix))
+		return
+	}
+	if v.Kind() == reflect.Struct && v.NumField() == 0 || v.Kind() != reflect.Struct && v.Len() == 0 {
+		r.line(op, indent, label+t.String()+"{}"+suffix)
+		return
+	}
+	if r.MaxDepth > 0 && depth >= r.MaxDepth {
+		r.line(op, indent, label+t.String()+"{...}"+suffix)
+		return
+	}
+
+	r.line(op, indent, label+t.String()+"{")
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			r.value(op, t.Field(i).Name+": ", ",", indent+1, v.Field(i), false, depth+1)
+		}
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			r.value(op, "", ",", indent+1, v.Index(i), false, depth+1)
+		}
+	case reflect.Map:
+		for _, k := range sortedKeys(v) {
+			r.value(op, fmt.Sprintf("%#v: ", k), ",", indent+1, v.MapIndex(k), false, depth+1)
+		}
+	}
+	r.line(op, indent, "}"+suffix)
+}
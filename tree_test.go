@@ -0,0 +1,160 @@
+package deep
+
+import (
+	"strings"
+	"testing"
+)
+
+type treeRecord struct {
+	Name  string
+	ID    int
+	Tags  []string
+	Attrs map[string]int
+	Ptr   *basic
+	Any   interface{}
+	Note  string
+}
+
+func TestTreeDiff(t *testing.T) {
+	c := newComparer("MaxDiffs", 100)
+	if s := c.TreeDiff(basic{1, 2}, basic{1, 2}); s != "" {
+		t.Errorf("want empty string for equal values, got %q", s)
+	}
+
+	x := treeRecord{
+		Name:  "a",
+		ID:    1,
+		Tags:  []string{"x", "y", "z"},
+		Attrs: map[string]int{"k": 1, "gone": 2, "same": 3},
+		Ptr:   &basic{1, 2},
+		Any:   1,
+		Note:  "n",
+	}
+	y := treeRecord{
+		Name:  "b",
+		ID:    1,
+		Tags:  []string{"y", "w", "z", "v"},
+		Attrs: map[string]int{"k": 2, "same": 3},
+		Ptr:   &basic{1, 3},
+		Any:   "1",
+		Note:  "n",
+	}
+	want := strings.Join([]string{
+		"  deep.treeRecord{",
+		"- \tName: \"a\",",
+		"+ \tName: \"b\",",
+		"  \t... // 1 unchanged field",
+		"  \tTags: []string{",
+		"- \t\t\"x\",",
+		"  \t\t... // 1 unchanged element",
+		"+ \t\t\"w\",",
+		"  \t\t... // 1 unchanged element",
+		"+ \t\t\"v\",",
+		"  \t},",
+		"  \tAttrs: map[string]int{",
+		"- \t\t\"gone\": 2,",
+		"- \t\t\"k\": 1,",
+		"+ \t\t\"k\": 2,",
+		"  \t\t... // 1 unchanged entry",
+		"  \t},",
+		"  \tPtr: &deep.basic{",
+		"  \t\t... // 1 unchanged field",
+		"- \t\tY: 2,",
+		"+ \t\tY: 3,",
+		"  \t},",
+		"- \tAny: int(1),",
+		"+ \tAny: string(\"1\"),",
+		"  \t... // 1 unchanged field",
+		"  }",
+	}, "\n")
+	if got := c.TreeDiff(x, y); got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestTreeDiffValues(t *testing.T) {
+	c := newComparer("MaxDiffs", 100)
+	tests := []struct {
+		x, y interface{}
+		want string
+	}{
+		{1, 2, "- 1\n+ 2"},
+		{nil, 1, "- nil\n+ 1"},
+		{[]int(nil), []int{}, "- []int(nil)\n+ []int{}"},
+		{[]byte("ab"), []byte("ac"), "  []uint8{\n  \t... // 1 unchanged element\n- \t0x62,\n+ \t0x63,\n  }"},
+		{[]int{1, 2, 3}, []int{1, 3}, "  []int{\n  \t... // 1 unchanged element\n- \t2,\n  \t... // 1 unchanged element\n  }"},
+		{
+			[]interface{}{basic{1, 2}},
+			[]interface{}{map[int][]int{1: {2}}},
+			"  []interface {}{\n" +
+				"- \tdeep.basic{\n" +
+				"- \t\tX: 1,\n" +
+				"- \t\tY: 2,\n" +
+				"- \t},\n" +
+				"+ \tmap[int][]int{\n" +
+				"+ \t\t1: []int{\n" +
+				"+ \t\t\t2,\n" +
+				"+ \t\t},\n" +
+				"+ \t},\n" +
+				"  }",
+		},
+		{(*basic)(nil), &basic{}, "- (*deep.basic)(nil)\n+ &deep.basic{\n+ \tX: 0,\n+ \tY: 0,\n+ }"},
+	}
+	for i, test := range tests {
+		if got := c.TreeDiff(test.x, test.y); got != test.want {
+			t.Errorf("[%d]: want\n%s\ngot\n%s", i, test.want, got)
+		}
+	}
+
+	// Cycles are not followed.
+	type node struct{ Next *node }
+	cyc := &node{}
+	cyc.Next = cyc
+	got := c.TreeDiff([]*node{}, []*node{cyc})
+	want := "  []*deep.node{\n+ \t&deep.node{\n+ \t\tNext: &deep.node{...}, // cycle\n+ \t},\n  }"
+	if got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+
+	// Values nested beyond MaxDepth are elided.
+	c.MaxDepth = 2
+	got = c.TreeDiff([][][]int{{{1}}}, [][][]int{{{1}}, {{2}}})
+	want = "  [][][]int{\n  \t... // 1 unchanged element\n+ \t[][]int{\n+ \t\t[]int{...},\n+ \t},\n  }"
+	if got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+	// Values that were not compared are marked.
+	c.MaxDepth = 1
+	c.ReportMaxDepth = true
+	got = c.TreeDiff([]basic{{1, 2}}, []basic{{1, 2}})
+	want = "  []deep.basic{\n  \tdeep.basic{\n  \t\tX: ..., // not compared beyond MaxDepth\n  \t\tY: ..., // not compared beyond MaxDepth\n  \t},\n  }"
+	if got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestTreeDiffMaxDiffs(t *testing.T) {
+	c := newComparer("MaxDiffs", 2)
+	type T struct{ A, B, C, D int }
+	got := c.TreeDiff(T{1, 1, 1, 1}, T{2, 2, 2, 2})
+	want := "  deep.T{\n" +
+		"- \tA: 1,\n+ \tA: 2,\n" +
+		"- \tB: 1,\n+ \tB: 2,\n" +
+		"- \tC: 1,\n+ \tC: 2,\n" +
+		"- \tD: 1,\n+ \tD: 2,\n" +
+		"  }"
+	if got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+
+	got = c.TreeDiff([]int{1, 2, 3, 4}, []int{5, 2, 6, 7})
+	want = "  []int{\n" +
+		"- \t1,\n+ \t5,\n" +
+		"  \t... // 1 unchanged element\n" +
+		"- \t3,\n+ \t6,\n" +
+		"- \t4,\n+ \t7,\n" +
+		"  }"
+	if got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}